@@ -0,0 +1,140 @@
+package libpq
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errArrayFormat = errors.New("libpq: invalid array format")
+
+// parseArray splits the text representation of a one-dimensional array into
+// its elements, with nil standing for NULL. Most types use ',' as delim; box
+// uses ';'.
+func parseArray(src string, delim byte) ([]*string, error) {
+	// skip explicit bounds, e.g. "[0:1]={a,b}"
+	if strings.HasPrefix(src, "[") {
+		eq := strings.IndexByte(src, '=')
+		if eq < 0 {
+			return nil, errArrayFormat
+		}
+		src = src[eq+1:]
+	}
+	if len(src) < 2 || src[0] != '{' || src[len(src)-1] != '}' {
+		return nil, errArrayFormat
+	}
+	src = src[1 : len(src)-1]
+
+	var elems []*string
+	if src == "" {
+		return elems, nil
+	}
+	for i := 0; ; i++ {
+		var elem string
+		var quoted bool
+		if i < len(src) && src[i] == '"' {
+			// quoted element; backslash escapes the next character
+			var b strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) {
+					return nil, errArrayFormat
+				}
+				if src[i] == '\\' {
+					i++
+					if i >= len(src) {
+						return nil, errArrayFormat
+					}
+				} else if src[i] == '"' {
+					i++
+					break
+				}
+				b.WriteByte(src[i])
+			}
+			elem, quoted = b.String(), true
+		} else {
+			if i < len(src) && src[i] == '{' {
+				return nil, errors.New("libpq: multidimensional arrays are not supported")
+			}
+			end := strings.IndexByte(src[i:], delim)
+			if end < 0 {
+				end = len(src) - i
+			}
+			elem = src[i : i+end]
+			i += end
+		}
+
+		if !quoted && strings.EqualFold(elem, "NULL") {
+			elems = append(elems, nil)
+		} else {
+			elems = append(elems, &elem)
+		}
+
+		if i >= len(src) {
+			return elems, nil
+		}
+		if src[i] != delim {
+			return nil, errArrayFormat
+		}
+	}
+}
+
+// formatArray builds the text representation of a one-dimensional array from
+// the text representations of its elements, with nil standing for NULL.
+func formatArray(elems []*string, delim byte) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, elem := range elems {
+		if i > 0 {
+			b.WriteByte(delim)
+		}
+		if elem == nil {
+			b.WriteString("NULL")
+			continue
+		}
+		b.WriteByte('"')
+		for j := 0; j < len(*elem); j++ {
+			if c := (*elem)[j]; c == '"' || c == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte((*elem)[j])
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// scanArray parses the array handed to a Scan method, converting each
+// element with parse; into names the type being scanned into. A NULL array
+// is returned as nil, and NULL elements are an error.
+func scanArray[T any](src interface{}, into string, parse func(string) (T, error)) ([]T, error) {
+	s, null, err := srcText(src, into)
+	if err != nil || null {
+		return nil, err
+	}
+	elems, err := parseArray(s, ',')
+	if err != nil {
+		return nil, err
+	}
+
+	arr := make([]T, len(elems))
+	for i, elem := range elems {
+		if elem == nil {
+			return nil, fmt.Errorf("libpq: cannot scan NULL element into %s", into)
+		}
+		if arr[i], err = parse(*elem); err != nil {
+			return nil, err
+		}
+	}
+	return arr, nil
+}
+
+// valueArray formats a as an array, converting each element with format.
+func valueArray[T any](a []T, format func(T) string) string {
+	elems := make([]*string, len(a))
+	for i := range a {
+		s := format(a[i])
+		elems[i] = &s
+	}
+	return formatArray(elems, ',')
+}
@@ -0,0 +1,319 @@
+package libpq
+
+/*
+#include <errno.h>
+#include <poll.h>
+#include <stdlib.h>
+#include <libpq-fe.h>
+
+// Pipeline mode arrived in libpq 14; older versions get stubs that always
+// fail, and SendBatch returns ErrPipelineUnsupported.
+#ifdef LIBPQ_HAS_PIPELINING
+#define HAS_PIPELINING 1
+#define PIPELINE_SYNC PGRES_PIPELINE_SYNC
+#define PIPELINE_ABORTED PGRES_PIPELINE_ABORTED
+#else
+#define HAS_PIPELINING 0
+#define PIPELINE_SYNC 100
+#define PIPELINE_ABORTED 101
+static int PQenterPipelineMode(PGconn *conn) { return 0; }
+static int PQexitPipelineMode(PGconn *conn) { return 0; }
+static int PQpipelineSync(PGconn *conn) { return 0; }
+#endif
+
+// flushPipeline sends everything queued on a nonblocking connection, reading
+// input as it arrives so that neither side blocks on a full socket buffer.
+static int flushPipeline(PGconn *conn) {
+	int r;
+	struct pollfd pfd;
+
+	pfd.fd = PQsocket(conn);
+	if (pfd.fd < 0) {
+		return -1;
+	}
+	pfd.events = POLLIN | POLLOUT;
+
+	while ((r = PQflush(conn)) == 1) {
+		if (poll(&pfd, 1, -1) < 0) {
+			if (errno == EINTR) {
+				continue;
+			}
+			return -1;
+		}
+		if ((pfd.revents & POLLIN) && !PQconsumeInput(conn)) {
+			return -1;
+		}
+	}
+	return r;
+}
+*/
+import "C"
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+var (
+	// Error returned by SendBatch() if libpq was built without pipeline mode.
+	ErrPipelineUnsupported = errors.New("libpq: pipeline mode needs libpq 14 or later")
+
+	// Error given to the queries of a batch following one that failed, which
+	// the server skipped.
+	ErrBatchAborted = errors.New("libpq: query not run: an earlier query in the batch failed")
+)
+
+// Batch is a list of queries sent to the server together using pipeline
+// mode, in a single round trip:
+//
+//	b := new(libpq.Batch)
+//	for _, item := range items {
+//		b.Queue("INSERT INTO items VALUES ($1, $2)", item.ID, item.Name)
+//	}
+//	conn.Raw(func(dc interface{}) error {
+//		_, err := dc.(libpq.Conn).SendBatch(b)
+//		return err
+//	})
+type Batch struct {
+	queries []batchQuery
+}
+
+type batchQuery struct {
+	query string
+	args  []interface{}
+}
+
+// Queue adds a query to the batch. Parameters are the same as for Exec, and
+// may be sql.NamedArg values for queries with named placeholders.
+func (b *Batch) Queue(query string, args ...interface{}) {
+	b.queries = append(b.queries, batchQuery{query, args})
+}
+
+// Len returns the number of queries in the batch.
+func (b *Batch) Len() int {
+	return len(b.queries)
+}
+
+// BatchResult is the outcome of one query in a batch.
+type BatchResult struct {
+	RowsAffected int64
+	Columns      []string
+	Rows         [][]driver.Value
+
+	// Err is the query's error, or ErrBatchAborted if the server skipped it
+	// because an earlier query failed.
+	Err error
+}
+
+// SendBatch sends the queries in b in pipeline mode and returns their
+// results in order. If a query fails, SendBatch returns its error along with
+// the results.
+//
+// Unless the batch runs inside a transaction, its queries make up one
+// implicit transaction: when one fails, the ones before it are rolled back
+// and the ones after it are skipped.
+//
+// If the batch can't be sent, or the connection can't leave pipeline mode
+// afterwards, the connection is left in an unknown state: every result gets
+// the error, SendBatch returns driver.ErrBadConn, and database/sql discards
+// the connection.
+func (c *libpqConn) SendBatch(b *Batch) ([]BatchResult, error) {
+	if C.HAS_PIPELINING == 0 {
+		return nil, ErrPipelineUnsupported
+	}
+	if len(b.queries) == 0 {
+		return nil, nil
+	}
+	if err := c.ensureConn(); err != nil {
+		return nil, err
+	}
+
+	// Check and encode every query's parameters before sending anything, so
+	// bad ones fail the whole batch. (Encoding some types also needs a round
+	// trip to the server, which can't happen in pipeline mode.)
+	queries := make([]string, len(b.queries))
+	values := make([][]driver.Value, len(b.queries))
+	for i, q := range b.queries {
+		var err error
+		if queries[i], values[i], err = c.batchArgs(q.query, q.args); err == nil {
+			var a *cArgs
+			if a, err = c.buildCArgs(values[i]); err == nil {
+				a.free()
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("libpq: batch query %d: %s", i+1, err)
+		}
+	}
+
+	if C.PQenterPipelineMode(c.db) != 1 {
+		return nil, errors.New("libpq: could not enter pipeline mode: " + C.GoString(C.PQerrorMessage(c.db)))
+	}
+
+	sent, synced, sendErr := c.sendPipeline(queries, values)
+
+	results := make([]BatchResult, len(queries))
+	if !synced {
+		// there's no telling what reached the server or what it will send
+		c.broken = true
+		for i := range results {
+			results[i].Err = sendErr
+		}
+		return results, driver.ErrBadConn
+	}
+
+	// Results are only decoded after leaving pipeline mode, since decoding
+	// a type the batch created needs a query to look it up.
+	cresults := make([]*C.PGresult, len(queries))
+	defer func() {
+		for _, cres := range cresults {
+			if cres != nil {
+				C.PQclear(cres)
+			}
+		}
+	}()
+	for i := range results {
+		if i >= sent {
+			results[i].Err = sendErr
+			continue
+		}
+		cres := C.PQgetResult(c.db)
+		if cres == nil {
+			results[i].Err = errors.New("libpq: missing batch result: " + C.GoString(C.PQerrorMessage(c.db)))
+			continue
+		}
+		cresults[i] = cres
+		// each query's results end with a nil one
+		for cres = C.PQgetResult(c.db); cres != nil; cres = C.PQgetResult(c.db) {
+			C.PQclear(cres)
+		}
+	}
+
+	// and the whole pipeline's with the sync
+	var syncErr error
+	cres := C.PQgetResult(c.db)
+	if cres == nil || C.PQresultStatus(cres) != C.PIPELINE_SYNC {
+		syncErr = errors.New("libpq: batch not synced: " + C.GoString(C.PQerrorMessage(c.db)))
+	}
+	C.PQclear(cres)
+
+	if C.PQexitPipelineMode(c.db) != 1 {
+		// results are still pending, so later queries would fail
+		c.broken = true
+		return results, driver.ErrBadConn
+	}
+
+	var firstErr error
+	for i := range results {
+		r := &results[i]
+		if cres := cresults[i]; cres != nil {
+			cresults[i] = nil
+			c.batchResult(r, cres)
+		}
+		if firstErr == nil && r.Err != nil && r.Err != ErrBatchAborted {
+			firstErr = r.Err
+		}
+	}
+	if syncErr != nil {
+		firstErr = syncErr
+	}
+	return results, firstErr
+}
+
+// batchArgs converts a batch query's parameters as database/sql would for
+// Exec, rewriting named placeholders if there are named parameters.
+func (c *libpqConn) batchArgs(query string, args []interface{}) (string, []driver.Value, error) {
+	nvs := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		nvs[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+		if na, ok := arg.(sql.NamedArg); ok {
+			nvs[i].Name, nvs[i].Value = na.Name, na.Value
+		}
+		if err := c.CheckNamedValue(&nvs[i]); err != nil {
+			return "", nil, err
+		}
+	}
+	var names []string
+	if hasNamed(nvs) {
+		query, names = rewriteNamed(query, c.standardConformingStrings())
+	}
+	values, err := bindArgs(names, nvs)
+	return query, values, err
+}
+
+// sendPipeline queues queries with their parameters and a sync, then sends
+// them, returning how many were queued and whether the sync was sent. The
+// connection is nonblocking while queuing, so that libpq buffers the queries
+// rather than waiting on the server.
+func (c *libpqConn) sendPipeline(queries []string, values [][]driver.Value) (int, bool, error) {
+	C.PQsetnonblocking(c.db, 1)
+	defer C.PQsetnonblocking(c.db, 0)
+
+	sent := 0
+	var err error
+	for ; sent < len(queries); sent++ {
+		if err = c.sendQuery(queries[sent], values[sent]); err != nil {
+			break
+		}
+	}
+	if C.PQpipelineSync(c.db) != 1 || C.flushPipeline(c.db) != 0 {
+		// nothing more will come back from the server
+		return 0, false, errors.New("libpq: could not send batch: " + C.GoString(C.PQerrorMessage(c.db)))
+	}
+	return sent, true, err
+}
+
+func (c *libpqConn) sendQuery(query string, args []driver.Value) error {
+	cargs, err := c.buildCArgs(args)
+	if err != nil {
+		return err
+	}
+	defer cargs.free()
+
+	cquery := C.CString(query)
+	defer C.free(unsafe.Pointer(cquery))
+
+	if C.PQsendQueryParams(c.db, cquery, C.int(len(args)), cargs.types, cargs.values, cargs.lengths, cargs.formats, 0) != 1 {
+		return errors.New("libpq: could not send batch query: " + C.GoString(C.PQerrorMessage(c.db)))
+	}
+	return nil
+}
+
+// batchResult fills in r from cres, which it clears.
+func (c *libpqConn) batchResult(r *BatchResult, cres *C.PGresult) {
+	if C.PQresultStatus(cres) == C.PIPELINE_ABORTED {
+		C.PQclear(cres)
+		r.Err = ErrBatchAborted
+		return
+	}
+	if r.Err = resultError(cres); r.Err != nil {
+		C.PQclear(cres)
+		return
+	}
+	r.RowsAffected, r.Err = getNumRows(cres)
+
+	rows := &libpqRows{
+		s:     &libpqStmt{c: c},
+		res:   cres,
+		ncols: int(C.PQnfields(cres)),
+		nrows: int(C.PQntuples(cres)),
+	}
+	defer rows.Close()
+	if rows.ncols == 0 {
+		return
+	}
+	r.Columns = rows.Columns()
+	for {
+		dest := make([]driver.Value, rows.ncols)
+		if err := rows.Next(dest); err == io.EOF {
+			break
+		} else if err != nil {
+			r.Err = err
+			return
+		}
+		r.Rows = append(r.Rows, dest)
+	}
+}
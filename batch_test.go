@@ -0,0 +1,116 @@
+package libpq_test
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func sendBatch(t *testing.T, db *sql.DB, b *libpq.Batch) (results []libpq.BatchResult, err error) {
+	conn, cerr := db.Conn(context.Background())
+	if cerr != nil {
+		t.Fatal(cerr)
+	}
+	defer conn.Close()
+	cerr = conn.Raw(func(dc interface{}) error {
+		results, err = dc.(libpq.Conn).SendBatch(b)
+		return nil
+	})
+	if cerr != nil {
+		t.Fatal(cerr)
+	}
+	return results, err
+}
+
+func TestBatch(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	mustExec(t, db, "DROP TABLE IF EXISTS test")
+	mustExec(t, db, "CREATE TABLE test (a int primary key, b text)")
+	defer db.Exec("DROP TABLE test")
+
+	b := new(libpq.Batch)
+	for i := 1; i <= 100; i++ {
+		b.Queue("insert into test values ($1, $2)", i, nil)
+	}
+	b.Queue("update test set b = @b where a > @a", sql.Named("a", 90), sql.Named("b", "x"))
+	b.Queue("select count(*), count(b) from test")
+	results, err := sendBatch(t, db, b)
+	if err != nil {
+		t.Fatalf("Failed to send batch: %s", err)
+	}
+	if len(results) != b.Len() {
+		t.Fatalf("Expected %d results, got %d", b.Len(), len(results))
+	}
+	if results[0].RowsAffected != 1 || results[100].RowsAffected != 10 {
+		t.Errorf("Unexpected rows affected: %d, %d", results[0].RowsAffected, results[100].RowsAffected)
+	}
+	last := results[101]
+	if len(last.Columns) != 2 || len(last.Rows) != 1 || last.Rows[0][0] != int64(100) || last.Rows[0][1] != int64(10) {
+		t.Errorf("Unexpected select result %+v", last)
+	}
+
+	// a failure part way through rolls back the queries before it and skips
+	// the ones after it
+	b = new(libpq.Batch)
+	b.Queue("insert into test values (101, 'ok')")
+	b.Queue("insert into test values (1, 'duplicate')")
+	b.Queue("insert into test values (102, 'skipped')")
+	results, err = sendBatch(t, db, b)
+	if err == nil || !strings.Contains(err.Error(), "duplicate key") {
+		t.Errorf("Expected duplicate key error, got %v", err)
+	}
+	if len(results) != 3 || results[0].Err != nil || results[1].Err != err || results[2].Err != libpq.ErrBatchAborted {
+		t.Errorf("Unexpected results %+v", results)
+	}
+	var n int
+	if err := db.QueryRow("select count(*) from test where a > 100").Scan(&n); err != nil || n != 0 {
+		t.Errorf("Expected the failed batch to be rolled back, found %d rows (err=%v)", n, err)
+	}
+
+	// bad parameters fail the batch before anything is sent
+	b = new(libpq.Batch)
+	b.Queue("insert into test values (103, 'not sent')")
+	b.Queue("select $1", struct{}{})
+	if _, err := sendBatch(t, db, b); err == nil || !strings.Contains(err.Error(), "batch query 2") {
+		t.Errorf("Expected parameter error, got %v", err)
+	}
+
+	// and the connection is usable afterwards
+	if err := db.QueryRow("select count(*) from test").Scan(&n); err != nil || n != 100 {
+		t.Errorf("Expected 100 rows, got %d (err=%v)", n, err)
+	}
+}
+
+func TestBatchNewType(t *testing.T) {
+	setup := getConn(t)
+	defer setup.Close()
+	mustExec(t, setup, "DROP TYPE IF EXISTS test_batch_mood")
+	defer setup.Exec("DROP TYPE IF EXISTS test_batch_mood")
+
+	codecs := libpq.NewCodecs()
+	if err := codecs.RegisterEnum("test_batch_mood", reflect.TypeOf(testMood(""))); err != nil {
+		t.Fatal(err)
+	}
+	c := libpq.NewConnector(testDSN())
+	c.Codecs = codecs
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	// the type is looked up once the batch is done, not while pipelining
+	b := new(libpq.Batch)
+	b.Queue("create type test_batch_mood as enum ('ok')")
+	b.Queue("select 'ok'::test_batch_mood")
+	results, err := sendBatch(t, db, b)
+	if err != nil {
+		t.Fatalf("Failed to send batch: %s", err)
+	}
+	if rows := results[1].Rows; len(rows) != 1 || rows[0][0] != testMood("ok") {
+		t.Errorf("Expected the new type to be decoded with its codec, got %+v", results[1])
+	}
+}
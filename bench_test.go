@@ -0,0 +1,76 @@
+package libpq_test
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// benchParallel runs query with args from many goroutines sharing a pool of
+// connections, as a busy server would.
+func benchParallel(b *testing.B, query string, args ...interface{}) {
+	db := getConn(b)
+	defer db.Close()
+	db.SetMaxOpenConns(runtime.GOMAXPROCS(0) * 2)
+	db.SetMaxIdleConns(runtime.GOMAXPROCS(0) * 2)
+
+	b.SetParallelism(16)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var n int
+		for pb.Next() {
+			if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkParamsParallel(b *testing.B) {
+	benchParallel(b, "select $1::int + $2::int", 1, 2)
+}
+
+// More parameters than a connection keeps arrays for between queries.
+func BenchmarkManyParamsParallel(b *testing.B) {
+	args := make([]interface{}, 300)
+	placeholders := make([]string, len(args))
+	for i := range args {
+		args[i] = i
+		placeholders[i] = fmt.Sprintf("$%d::int", i+1)
+	}
+	benchParallel(b, "select "+strings.Join(placeholders, " + "), args...)
+}
+
+// Allocations for large values, sent as parameters and read back.
+func benchLarge(b *testing.B, query string, args ...interface{}) {
+	db := getConn(b)
+	defer db.Close()
+
+	b.ReportAllocs()
+	b.SetBytes(1 << 20)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out []byte
+		if err := db.QueryRow(query, args...).Scan(&out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkByteaParam(b *testing.B) {
+	benchLarge(b, "select length($1::bytea)::text", make([]byte, 1<<20))
+}
+
+func BenchmarkTextParam(b *testing.B) {
+	benchLarge(b, "select length($1::text)::text", strings.Repeat("x", 1<<20))
+}
+
+func BenchmarkByteaResult(b *testing.B) {
+	benchLarge(b, "select repeat('x', 1 << 20)::bytea")
+}
+
+func BenchmarkTextResult(b *testing.B) {
+	benchLarge(b, "select repeat('x', 1 << 20)")
+}
@@ -0,0 +1,81 @@
+package libpq
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BitString is the value of a bit or bit varying column: Len bits, packed
+// most significant first into Bytes with the unused low bits of the last
+// byte zero.
+//
+// The zero BitString is the empty bit string.
+type BitString struct {
+	Bytes []byte
+	Len   int
+}
+
+// ParseBitString parses a string of '0's and '1's.
+func ParseBitString(s string) (BitString, error) {
+	b := BitString{Bytes: make([]byte, (len(s)+7)/8), Len: len(s)}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '1':
+			b.Bytes[i/8] |= 0x80 >> (i % 8)
+		case '0':
+		default:
+			return BitString{}, fmt.Errorf("libpq: invalid bit string %q", s)
+		}
+	}
+	return b, nil
+}
+
+// Bit returns the i'th bit of b, 0 or 1.
+func (b BitString) Bit(i int) int {
+	return int(b.Bytes[i/8]>>(7-i%8)) & 1
+}
+
+// String returns b as '0's and '1's, e.g. "10110".
+func (b BitString) String() string {
+	var s strings.Builder
+	s.Grow(b.Len)
+	for i := 0; i < b.Len; i++ {
+		s.WriteByte(byte('0' + b.Bit(i)))
+	}
+	return s.String()
+}
+
+// Implement Valuer interface.
+func (b BitString) Value() (driver.Value, error) {
+	return b.String(), nil
+}
+
+// Implement Scanner interface.
+func (b *BitString) Scan(src interface{}) error {
+	s, null, err := srcText(src, "BitString")
+	if err != nil {
+		return err
+	}
+	if null {
+		return errors.New("libpq: cannot scan NULL into BitString")
+	}
+	v, err := ParseBitString(s)
+	*b = v
+	return err
+}
+
+// decodeBitString converts the binary format of bit and varbit, the number
+// of bits followed by the bytes holding them, to text.
+func decodeBitString(src []byte) (string, error) {
+	if len(src) < 4 {
+		return "", errors.New("libpq: invalid binary bit string")
+	}
+	b := BitString{Len: int(binary.BigEndian.Uint32(src)), Bytes: src[4:]}
+	if len(b.Bytes) != (b.Len+7)/8 {
+		return "", errors.New("libpq: invalid binary bit string")
+	}
+	return b.String(), nil
+}
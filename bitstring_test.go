@@ -0,0 +1,38 @@
+package libpq_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func TestBitStringScan(t *testing.T) {
+	var b libpq.BitString
+	if err := b.Scan("101100001"); err != nil {
+		t.Fatalf("Failed to Scan() bit string: %s", err)
+	}
+	if b.Len != 9 || !bytes.Equal(b.Bytes, []byte{0xb0, 0x80}) || b.Bit(2) != 1 || b.Bit(1) != 0 {
+		t.Errorf("Unexpected bit string %+v", b)
+	}
+	if b.String() != "101100001" {
+		t.Errorf("Unexpected String() %q", b.String())
+	}
+	if err := b.Scan("102"); err == nil {
+		t.Errorf("Expected error scanning invalid bit string")
+	}
+}
+
+func TestBitString(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	in, _ := libpq.ParseBitString("1011")
+	var bit, varbit libpq.BitString
+	if err := db.QueryRow("select $1::bit(4), $1::varbit", in).Scan(&bit, &varbit); err != nil {
+		t.Fatalf("Failed to Scan() bit strings: %s", err)
+	}
+	if bit.String() != "1011" || varbit.String() != "1011" {
+		t.Errorf("Unexpected bit strings %s, %s", bit, varbit)
+	}
+}
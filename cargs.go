@@ -2,63 +2,120 @@ package libpq
 
 /*
 #include <stdlib.h>
+#include <libpq-fe.h>
 
-static char **makeCharArray(int size) {
-	return calloc(sizeof(char *), size);
+// parameter arrays for up to n parameters in one allocation: the values,
+// then their lengths, formats and types
+static void *makeArgArrays(int n) {
+	return calloc(n, sizeof(char *) + 2 * sizeof(int) + sizeof(Oid));
 }
 
-static void setArrayString(char **a, char *s, int n) {
-	a[n] = s;
+static void setArrayOid(Oid *a, Oid o, int n) {
+	a[n] = o;
 }
 
-static void freeArrayElements(int n, char **a) {
-	int i;
-	for (i = 0; i < n; i++) {
-		free(a[i]);
-		a[i] = NULL;
-	}
+static void setArrayInt(int *a, int i, int n) {
+	a[n] = i;
 }
 */
 import "C"
 import (
+	"bytes"
 	"database/sql/driver"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
 	"strconv"
 	"time"
+	"unsafe"
 )
 
 const timeFormat = time.RFC3339Nano
 
-// wrapper for a request for a char** of length nargs
-type pqPoolRequest struct {
-	nargs int
-	resp  chan (**C.char)
+// formatFloat formats f the way Postgres accepts, which for infinities is
+// not Go's "+Inf".
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
 }
 
-// return a char** of length nargs to the pool
-type pqPoolReturn struct {
-	nargs int
-	array **C.char
+// maxRetainedArgs and maxRetainedData are the most parameters and bytes of
+// parameter data a connection holds on to between queries; anything larger
+// is freed once the query is done.
+const (
+	maxRetainedArgs = 256
+	maxRetainedData = 1 << 20
+)
+
+// libpq-style parameter arrays for PQexecParams and PQexecPrepared, all in
+// the one C allocation at buf. Each connection reuses its own (see
+// libpqConn.cArgs), as a connection is only used by one goroutine at a time.
+// Zero types and formats leave parameters as untyped text.
+//
+// The parameters themselves are appended to data and then copied to C memory
+// in one piece, with values pointing into it. They stay valid until free,
+// by which time libpq has sent or copied them.
+type cArgs struct {
+	n   int // parameters in use
+	cap int // parameters buf has room for
+	buf unsafe.Pointer
+
+	values  **C.char
+	lengths *C.int
+	formats *C.int
+	types   *C.Oid
+
+	data     []byte
+	offsets  []int // of each parameter in data, or -1 for NULL
+	cdata    unsafe.Pointer
+	cdataCap int
 }
 
-var (
-	argpool     map[int][]**C.char
-	poolRequest chan pqPoolRequest
-	poolReturn  chan pqPoolReturn
-)
+// cArgs returns the connection's parameter arrays, with room for n
+// parameters. They must be given back with free.
+func (c *libpqConn) cArgs(n int) *cArgs {
+	if c.args == nil {
+		c.args = new(cArgs)
+	}
+	a := c.args
+	if n > a.cap {
+		C.free(a.buf)
+		a.buf = C.makeArgArrays(C.int(n))
+		a.cap = n
+		a.values = (**C.char)(a.buf)
+		a.lengths = (*C.int)(unsafe.Add(a.buf, uintptr(n)*unsafe.Sizeof(a.buf)))
+		a.formats = (*C.int)(unsafe.Add(unsafe.Pointer(a.lengths), uintptr(n)*unsafe.Sizeof(C.int(0))))
+		a.types = (*C.Oid)(unsafe.Add(unsafe.Pointer(a.formats), uintptr(n)*unsafe.Sizeof(C.int(0))))
+	}
+	a.n = n
+	a.offsets = a.offsets[:0]
+	for i := 0; i < n; i++ {
+		a.offsets = append(a.offsets, -1)
+	}
+	return a
+}
 
 // convert database/sql/driver arguments into libpq-style char**
-func buildCArgs(args []driver.Value) (**C.char, error) {
-	carray := getCharArrayFromPool(len(args))
+func (c *libpqConn) buildCArgs(args []driver.Value) (*cArgs, error) {
+	a := c.cArgs(len(args))
 
 	for i, v := range args {
+		start := len(a.data)
 		var str string
 		switch v := v.(type) {
 		case int64:
-			str = strconv.FormatInt(v, 10)
+			a.data = strconv.AppendInt(a.data, v, 10)
 		case float64:
-			str = strconv.FormatFloat(v, 'E', -1, 64)
+			str = formatFloat(v)
 		case bool:
 			if v {
 				str = "t"
@@ -66,55 +123,171 @@ func buildCArgs(args []driver.Value) (**C.char, error) {
 				str = "f"
 			}
 		case []byte:
-			str = `\x` + hex.EncodeToString(v)
+			a.data = hex.AppendEncode(append(a.data, `\x`...), v)
 		case string:
-			str = v
+			a.data = append(a.data, v...)
 		case time.Time:
-			str = v.Format(timeFormat)
+			a.data = v.AppendFormat(a.data, timeFormat)
+		case UUID:
+			str = v.String()
+			a.setType(i, oidUUID)
+		case [16]byte:
+			str = UUID(v).String()
+			a.setType(i, oidUUID)
+		case UUIDArray:
+			str = formatUUIDArray(v)
+			a.setType(i, oidUUIDArray)
+		case []UUID:
+			str = formatUUIDArray(v)
+			a.setType(i, oidUUIDArray)
+		case Interval:
+			str = v.String()
+			a.setType(i, oidInterval)
+		case time.Duration:
+			str = Interval{Microseconds: v.Microseconds()}.String()
+			a.setType(i, oidInterval)
+		case Money:
+			var err error
+			if str, err = c.formatMoney(v); err != nil {
+				a.free()
+				return nil, err
+			}
+			a.setType(i, oidMoney)
+		case netip.Addr, netip.Prefix, net.HardwareAddr, net.IP, *net.IPNet,
+			[]netip.Addr, []netip.Prefix, []net.HardwareAddr:
+			var err error
+			if str, _, err = encodeNetwork(v); err != nil {
+				a.free()
+				return nil, err
+			}
+		case Hstore, map[string]*string, map[string]string:
+			str, _ = encodeHstore(v)
+			a.setType(i, c.typeOid("hstore"))
+		case *big.Int, *big.Float, *big.Rat:
+			var err error
+			if str, err = encodeBig(v); err != nil {
+				a.free()
+				return nil, err
+			}
+			a.setType(i, oidNumeric)
+		case ParamEncoder:
+			b, typ, err := v.EncodeParam()
+			if err != nil {
+				a.free()
+				return nil, err
+			}
+			a.setType(i, oid(typ))
+			if b == nil {
+				continue
+			}
+			a.data = append(a.data, b...)
 		case nil:
-			str = "NULL"
+			// NULL is a null pointer in values
+			continue
 		default:
-			returnCharArrayToPool(len(args), carray)
-			return nil, errors.New("libpq: unsupported type")
+			if c.codecs.forValue(v) == nil {
+				a.free()
+				return nil, fmt.Errorf("libpq: parameter $%d: unsupported type %T", i+1, v)
+			}
+			if err := c.encodeWithCodec(a, i, v); err != nil {
+				a.free()
+				return nil, err
+			}
 		}
 
-		C.setArrayString(carray, C.CString(str), C.int(i))
+		a.data = append(a.data, str...)
+		if unsafe.Slice(a.formats, a.n)[i] == 0 && bytes.IndexByte(a.data[start:], 0) >= 0 {
+			// libpq would cut the value short at the NUL, and Postgres text
+			// cannot hold one anyway
+			a.free()
+			return nil, fmt.Errorf("libpq: parameter $%d: text contains a NUL byte; use []byte for binary data", i+1)
+		}
+		a.offsets[i] = start
+		C.setArrayInt(a.lengths, C.int(len(a.data)-start), C.int(i))
+		a.data = append(a.data, 0) // text parameters are C strings
 	}
 
-	return carray, nil
+	a.place()
+	return a, nil
 }
 
-func getCharArrayFromPool(nargs int) **C.char {
-	ch := make(chan **C.char)
-	req := pqPoolRequest{nargs, ch}
-	poolRequest <- req
-	return <-ch
-}
+// place copies the parameters' data to C memory and points values at it.
+func (a *cArgs) place() {
+	if len(a.data) == 0 {
+		return
+	}
+	if len(a.data) > a.cdataCap {
+		C.free(a.cdata)
+		a.cdataCap = cap(a.data)
+		a.cdata = C.malloc(C.size_t(a.cdataCap))
+	}
+	copy(unsafe.Slice((*byte)(a.cdata), len(a.data)), a.data)
 
-func returnCharArrayToPool(nargs int, array **C.char) {
-	C.freeArrayElements(C.int(nargs), array)
-	poolReturn <- pqPoolReturn{nargs, array}
+	values := unsafe.Slice(a.values, a.n)
+	for i, off := range a.offsets {
+		if off >= 0 {
+			values[i] = (*C.char)(unsafe.Add(a.cdata, off))
+		}
+	}
 }
 
-// this is started in a goroutine in libpq's init() to reduce the number
-// of calls to makeCharArray()
-func handleArgpool() {
-	argpool = make(map[int][]**C.char)
-	poolRequest = make(chan pqPoolRequest)
-	poolReturn = make(chan pqPoolReturn)
-	for {
-		select {
-		case req := <-poolReturn:
-			list := append(argpool[req.nargs], req.array)
-			argpool[req.nargs] = list
-
-		case req := <-poolRequest:
-			list := argpool[req.nargs]
-			if len(list) == 0 {
-				list = append(list, C.makeCharArray(C.int(req.nargs)))
-			}
-			req.resp <- list[0]
-			argpool[req.nargs] = list[1:]
+// encodeWithCodec sets parameter i to v as encoded by its registered Codec.
+func (c *libpqConn) encodeWithCodec(a *cArgs, i int, v interface{}) error {
+	ref := c.codecs.forValue(v)
+	if ref == nil {
+		return errors.New("libpq: unsupported type")
+	}
+
+	typ := c.resolveOid(ref)
+	a.setType(i, typ)
+
+	if ref.codec.EncodeBinary != nil {
+		b, err := ref.codec.EncodeBinary(v)
+		if err != nil {
+			return err
 		}
+		a.data = append(a.data, b...)
+		C.setArrayInt(a.formats, 1, C.int(i))
+		return nil
+	}
+
+	if ref.codec.EncodeText == nil {
+		return errors.New("libpq: codec cannot encode parameters")
 	}
+	b, err := c.encodeText(ref.codec, v)
+	if err != nil {
+		return err
+	}
+	if err := c.checkEnum(typ, string(b)); err != nil {
+		return err
+	}
+	a.data = append(a.data, b...)
+	return nil
+}
+
+// setType sends parameter i as type typ rather than leaving it to the server
+// to infer.
+func (a *cArgs) setType(i int, typ oid) {
+	C.setArrayOid(a.types, C.Oid(typ), C.int(i))
+}
+
+// free clears the arrays for reuse, or frees them if they are larger than a
+// connection should hold on to.
+func (a *cArgs) free() {
+	clear(unsafe.Slice(a.values, a.n))
+	clear(unsafe.Slice(a.lengths, a.n))
+	clear(unsafe.Slice(a.formats, a.n))
+	clear(unsafe.Slice(a.types, a.n))
+	a.n = 0
+	a.data = a.data[:0]
+	if a.cap > maxRetainedArgs || a.cdataCap > maxRetainedData {
+		a.release()
+	}
+}
+
+// release frees the arrays and data.
+func (a *cArgs) release() {
+	C.free(a.buf)
+	C.free(a.cdata)
+	*a = cArgs{}
 }
@@ -0,0 +1,191 @@
+package libpq
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// Codec converts values of one Postgres type to and from Go. Any of its
+// functions may be nil if that direction or format is not supported.
+//
+// The src slices passed to the decode functions are only valid for the
+// duration of the call.
+type Codec struct {
+	// Convert the text or binary representation of a column value into the
+	// value handed to database/sql (and from there to Scan).
+	DecodeText   func(src []byte) (interface{}, error)
+	DecodeBinary func(src []byte) (interface{}, error)
+
+	// Convert a query parameter into its text or binary representation. If
+	// EncodeBinary is set, parameters are sent in binary format.
+	EncodeText   func(v interface{}) ([]byte, error)
+	EncodeBinary func(v interface{}) ([]byte, error)
+
+	// Go types whose values are encoded by this codec when passed as query
+	// parameters.
+	GoTypes []reflect.Type
+
+	// Used instead of EncodeText, if set, by codecs the driver registers
+	// itself whose values may need the connection (e.g. composites with a
+	// Money attribute).
+	encodeConn func(c *libpqConn, v interface{}) ([]byte, error)
+}
+
+// Codecs is a registry of Codecs for types the driver does not handle itself,
+// such as domains, extension types or application-specific mappings.
+// Registering a codec for a type the driver does handle overrides the
+// driver's decoding of it.
+//
+// Codecs are looked up in the registry of the Connector a connection was made
+// with, falling back to DefaultCodecs. It is safe to register codecs while
+// connections are in use, although rows already being read are not affected.
+type Codecs struct {
+	mu        sync.RWMutex
+	byOid     map[oid]*Codec
+	byName    map[string]*Codec
+	byArrayOf map[string]*Codec // by element type name
+	byType    map[reflect.Type]*codecRef
+}
+
+// codecRef is a registered Codec along with the type it was registered for.
+type codecRef struct {
+	codec   *Codec
+	name    string // "" if registered by OID
+	arrayOf bool   // registered for arrays of type name
+	oid     oid
+}
+
+// DefaultCodecs is used by every connection.
+var DefaultCodecs = NewCodecs()
+
+// NewCodecs returns an empty registry, to be set as a Connector's Codecs.
+func NewCodecs() *Codecs {
+	return &Codecs{
+		byOid:     make(map[oid]*Codec),
+		byName:    make(map[string]*Codec),
+		byArrayOf: make(map[string]*Codec),
+		byType:    make(map[reflect.Type]*codecRef),
+	}
+}
+
+// RegisterType registers codec for the type with the given (possibly
+// schema-qualified) name. Its OID is looked up separately on each server, so
+// this is the way to register codecs for extension and user-defined types.
+func (r *Codecs) RegisterType(name string, codec *Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byName[name] = codec
+	for _, t := range codec.GoTypes {
+		r.byType[t] = &codecRef{codec: codec, name: name}
+	}
+}
+
+// RegisterOID registers codec for the type with the given OID. This should
+// only be used for built-in types, whose OIDs are the same on every server.
+func (r *Codecs) RegisterOID(typeOid uint32, codec *Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byOid[oid(typeOid)] = codec
+	for _, t := range codec.GoTypes {
+		r.byType[t] = &codecRef{codec: codec, oid: oid(typeOid)}
+	}
+}
+
+// registerArrayOf registers codec for arrays of the type with the given
+// (possibly schema-qualified) name. The array type is found from pg_type on
+// each server, rather than by guessing its name.
+func (r *Codecs) registerArrayOf(name string, codec *Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byArrayOf[name] = codec
+	for _, t := range codec.GoTypes {
+		r.byType[t] = &codecRef{codec: codec, name: name, arrayOf: true}
+	}
+}
+
+// registries to search, in order
+func (r *Codecs) chain() []*Codecs {
+	if r == DefaultCodecs {
+		return []*Codecs{r}
+	}
+	return []*Codecs{r, DefaultCodecs}
+}
+
+// forColumn finds the codec for column values of type typ, which is
+// described by t if it is not a built-in type, or returns nil.
+func (r *Codecs) forColumn(typ oid, t *pgType) *Codec {
+	for _, reg := range r.chain() {
+		reg.mu.RLock()
+		codec, ok := reg.byOid[typ]
+		if !ok && t != nil {
+			if codec, ok = reg.byName[t.schema+"."+t.name]; !ok {
+				codec, ok = reg.byName[t.name]
+			}
+		}
+		if !ok && t != nil && t.element != nil {
+			e := t.element
+			if codec, ok = reg.byArrayOf[e.schema+"."+e.name]; !ok {
+				codec, ok = reg.byArrayOf[e.name]
+			}
+		}
+		reg.mu.RUnlock()
+		if ok && (codec.DecodeText != nil || codec.DecodeBinary != nil) {
+			return codec
+		}
+	}
+	return nil
+}
+
+// forValue finds the codec for a parameter v, or nil.
+func (r *Codecs) forValue(v interface{}) *codecRef {
+	t := reflect.TypeOf(v)
+	for _, reg := range r.chain() {
+		reg.mu.RLock()
+		ref := reg.byType[t]
+		reg.mu.RUnlock()
+		if ref != nil {
+			return ref
+		}
+	}
+	return nil
+}
+
+// resolveOid returns the OID of the type ref was registered for on the
+// server c is connected to, or 0 (leaving it to the server to infer the type)
+// if that type does not exist there.
+func (c *libpqConn) resolveOid(ref *codecRef) oid {
+	if ref.name == "" {
+		return ref.oid
+	}
+	if ref.arrayOf {
+		if elem := c.typeByOid(c.typeOid(ref.name)); elem != nil {
+			return elem.array
+		}
+		return 0
+	}
+	return c.typeOid(ref.name)
+}
+
+// encodeText encodes v, a parameter, array element or attribute, with codec.
+func (c *libpqConn) encodeText(codec *Codec, v interface{}) ([]byte, error) {
+	if codec.encodeConn != nil {
+		return codec.encodeConn(c, v)
+	}
+	return codec.EncodeText(v)
+}
+
+// decodeWithCodec decodes column value src, sent in the given format (0 for
+// text, 1 for binary).
+func decodeWithCodec(codec *Codec, format int, src []byte) (interface{}, error) {
+	if format == 1 {
+		if codec.DecodeBinary == nil {
+			return nil, errors.New("libpq: codec cannot decode binary values")
+		}
+		return codec.DecodeBinary(src)
+	}
+	if codec.DecodeText == nil {
+		return nil, errors.New("libpq: codec cannot decode text values")
+	}
+	return codec.DecodeText(src)
+}
@@ -0,0 +1,126 @@
+package libpq_test
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+type testMood string
+
+func TestCodecs(t *testing.T) {
+	setup := getConn(t)
+	defer setup.Close()
+	mustExec(t, setup, "DROP TYPE IF EXISTS test_mood")
+	mustExec(t, setup, "CREATE TYPE test_mood AS ENUM ('sad', 'ok', 'happy')")
+	defer setup.Exec("DROP TYPE test_mood")
+
+	codecs := libpq.NewCodecs()
+	codecs.RegisterType("test_mood", &libpq.Codec{
+		DecodeText: func(src []byte) (interface{}, error) {
+			return testMood(strings.ToUpper(string(src))), nil
+		},
+		EncodeText: func(v interface{}) ([]byte, error) {
+			return []byte(strings.ToLower(string(v.(testMood)))), nil
+		},
+		GoTypes: []reflect.Type{reflect.TypeOf(testMood(""))},
+	})
+	c := libpq.NewConnector(testDSN())
+	c.Codecs = codecs
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	var mood testMood
+	if err := db.QueryRow("select 'happy'::test_mood").Scan(&mood); err != nil {
+		t.Fatalf("Failed to Scan() with a registered codec: %s", err)
+	}
+	if mood != "HAPPY" {
+		t.Fatalf("Codec not used for decoding (got '%s')", mood)
+	}
+
+	var same bool
+	if err := db.QueryRow("select $1 = 'ok'::test_mood", testMood("OK")).Scan(&same); err != nil {
+		t.Fatalf("Failed to pass a parameter with a registered codec: %s", err)
+	}
+	if !same {
+		t.Fatalf("Codec not used for encoding")
+	}
+
+	// connections from other connectors don't use the codec
+	other := getConn(t)
+	defer other.Close()
+	var s string
+	if err := other.QueryRow("select 'happy'::test_mood").Scan(&s); err != nil || s != "happy" {
+		t.Fatalf("Codec unexpectedly used by a different connector: %v (got '%s')", err, s)
+	}
+}
+
+func typeRegistry(t *testing.T, db *sql.DB) (reg interface{}) {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = conn.Raw(func(dc interface{}) error {
+		reg = libpq.TypeRegistry(dc)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reg
+}
+
+func TestTypeRegistry(t *testing.T) {
+	setup := getConn(t)
+	defer setup.Close()
+	mustExec(t, setup, "DROP TYPE IF EXISTS test_late")
+
+	// different DSNs reaching the same database share its types, however
+	// they reach it
+	db := sql.OpenDB(libpq.NewConnector(testDSN()))
+	defer db.Close()
+	reg := typeRegistry(t, db)
+	for _, extra := range []string{" application_name=libpq_test", " host=localhost"} {
+		other := sql.OpenDB(libpq.NewConnector(testDSN() + extra))
+		if err := other.Ping(); err != nil {
+			t.Logf("Skipping DSN with%s: %s", extra, err)
+		} else if typeRegistry(t, other) != reg {
+			t.Errorf("Connection with%s has a different type registry", extra)
+		}
+		other.Close()
+	}
+
+	// types created and changed after connecting are picked up
+	mustExec(t, setup, "CREATE TYPE test_late AS ENUM ('a', 'b')")
+	defer setup.Exec("DROP TYPE test_late")
+	codecs := libpq.NewCodecs()
+	if err := codecs.RegisterEnum("test_late", reflect.TypeOf(testMood(""))); err != nil {
+		t.Fatal(err)
+	}
+	c := libpq.NewConnector(testDSN())
+	c.Codecs = codecs
+	late := sql.OpenDB(c)
+	defer late.Close()
+	late.SetMaxOpenConns(1)
+	before := typeRegistry(t, late)
+
+	var s string
+	if err := late.QueryRow("select $1::text", testMood("b")).Scan(&s); err != nil || s != "b" {
+		t.Fatalf("Failed to pass a value of a new enum: %v (got %q)", err, s)
+	}
+	mustExec(t, setup, "ALTER TYPE test_late ADD VALUE 'c'")
+	if err := late.QueryRow("select $1::text", testMood("c")).Scan(&s); err != nil || s != "c" {
+		t.Fatalf("Failed to pass a new enum label: %v (got %q)", err, s)
+	}
+	if typeRegistry(t, late) == before {
+		t.Errorf("Type registry not reloaded after the enum changed")
+	}
+	if _, err := late.Exec("select $1::text", testMood("d")); err == nil || !strings.Contains(err.Error(), "invalid input value") {
+		t.Errorf("Expected invalid enum label error, got %v", err)
+	}
+}
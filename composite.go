@@ -0,0 +1,323 @@
+package libpq
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var errCompositeFormat = errors.New("libpq: invalid composite format")
+
+// compositeAttr is an attribute of a composite type, as found in
+// pg_attribute.
+type compositeAttr struct {
+	name     string
+	typ      oid
+	typeName string
+	field    []int // index of the struct field it maps to, or nil
+}
+
+// pgType describes the attribute's type well enough to find its codec.
+func (attr *compositeAttr) pgType() *pgType {
+	if name := strings.TrimSuffix(attr.typeName, "[]"); name != attr.typeName {
+		return &pgType{name: attr.typeName, element: &pgType{name: name}}
+	}
+	return &pgType{name: attr.typeName}
+}
+
+// compositeType is a composite type registered with RegisterComposite.
+type compositeType struct {
+	codecs *Codecs
+	attrs  []compositeAttr
+	goType reflect.Type // nil to decode into []interface{}
+}
+
+const compositeAttrsQuery = `SELECT a.attname, a.atttypid, a.atttypid::regtype::text
+	FROM pg_attribute a JOIN pg_type t ON t.typrelid = a.attrelid
+	WHERE t.oid = $1::regtype AND a.attnum > 0 AND NOT a.attisdropped
+	ORDER BY a.attnum`
+
+// RegisterComposite registers a codec for the composite type with the given
+// (possibly schema-qualified) name, looking up its attributes through db.
+//
+// If goType is nil, values of the type are decoded into a []interface{} of
+// their attributes. Otherwise goType must be a struct type; values are
+// decoded into it, and values of it passed as parameters are encoded as
+// record literals. Attributes map to exported fields by `db:"name"` tag
+// or, without one, by case-insensitive name; fields tagged `db:"-"` are
+// ignored. Attributes without a field are skipped when decoding and sent as
+// NULL when encoding.
+func (r *Codecs) RegisterComposite(db *sql.DB, name string, goType reflect.Type) error {
+	ct := &compositeType{codecs: r, goType: goType}
+
+	rows, err := db.Query(compositeAttrsQuery, name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var attr compositeAttr
+		var typ uint32
+		if err := rows.Scan(&attr.name, &typ, &attr.typeName); err != nil {
+			return err
+		}
+		attr.typ = oid(typ)
+		ct.attrs = append(ct.attrs, attr)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(ct.attrs) == 0 {
+		return fmt.Errorf("libpq: %s is not a composite type", name)
+	}
+
+	codec := &Codec{DecodeText: ct.decodeText, DecodeBinary: ct.decodeBinary}
+	if goType != nil {
+		if goType.Kind() != reflect.Struct {
+			return fmt.Errorf("libpq: cannot map composite type %s to %s", name, goType)
+		}
+		if err := ct.mapFields(); err != nil {
+			return err
+		}
+		codec.EncodeText = ct.encodeText
+		codec.encodeConn = ct.encode
+		codec.GoTypes = []reflect.Type{goType}
+	}
+	r.RegisterType(name, codec)
+	return nil
+}
+
+// mapFields matches the attributes of ct to the fields of its Go type.
+func (ct *compositeType) mapFields() error {
+	for i := 0; i < ct.goType.NumField(); i++ {
+		f := ct.goType.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+		found := false
+		for a := range ct.attrs {
+			attr := &ct.attrs[a]
+			if tag == attr.name || (tag == "" && strings.EqualFold(f.Name, attr.name)) {
+				attr.field, found = f.Index, true
+				break
+			}
+		}
+		if tag != "" && !found {
+			return fmt.Errorf("libpq: field %s of %s has no matching attribute", f.Name, ct.goType)
+		}
+	}
+	return nil
+}
+
+func (ct *compositeType) decodeText(src []byte) (interface{}, error) {
+	s := string(src)
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return nil, errCompositeFormat
+	}
+	s = s[1:]
+
+	vals := make([]interface{}, len(ct.attrs))
+	for i, attr := range ct.attrs {
+		elem, ok, rest, err := scanElement(s, ",)")
+		if err != nil {
+			return nil, errCompositeFormat
+		}
+		want := byte(',')
+		if i == len(ct.attrs)-1 {
+			want = ')'
+		}
+		if rest[0] != want {
+			return nil, errCompositeFormat
+		}
+		s = rest[1:]
+		if !ok {
+			continue
+		}
+
+		if codec := ct.codecs.forColumn(attr.typ, attr.pgType()); codec != nil {
+			vals[i], err = decodeWithCodec(codec, 0, []byte(elem))
+		} else {
+			vals[i], err = decodeText(attr.typ, elem)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s != "" {
+		return nil, errCompositeFormat
+	}
+	return ct.result(vals)
+}
+
+// decodeBinary decodes the binary record format: the number of attributes,
+// then the type OID, length (-1 for NULL) and value of each.
+func (ct *compositeType) decodeBinary(src []byte) (interface{}, error) {
+	if len(src) < 4 || int(binary.BigEndian.Uint32(src)) != len(ct.attrs) {
+		return nil, errCompositeFormat
+	}
+	src = src[4:]
+
+	vals := make([]interface{}, len(ct.attrs))
+	for i, attr := range ct.attrs {
+		if len(src) < 8 {
+			return nil, errCompositeFormat
+		}
+		n := int32(binary.BigEndian.Uint32(src[4:]))
+		src = src[8:]
+		if n < 0 {
+			continue
+		}
+		if int(n) > len(src) {
+			return nil, errCompositeFormat
+		}
+		elem := src[:n]
+		src = src[n:]
+
+		var err error
+		if codec := ct.codecs.forColumn(attr.typ, attr.pgType()); codec != nil {
+			vals[i], err = decodeWithCodec(codec, 1, elem)
+		} else {
+			vals[i], err = decodeBinary(attr.typ, elem)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ct.result(vals)
+}
+
+// result converts decoded attribute values into ct's Go type.
+func (ct *compositeType) result(vals []interface{}) (interface{}, error) {
+	if ct.goType == nil {
+		return vals, nil
+	}
+	v := reflect.New(ct.goType).Elem()
+	for i, attr := range ct.attrs {
+		if attr.field == nil {
+			continue
+		}
+		if err := assignValue(v.FieldByIndex(attr.field), vals[i]); err != nil {
+			return nil, fmt.Errorf("libpq: attribute %s: %s", attr.name, err)
+		}
+	}
+	return v.Interface(), nil
+}
+
+// assignValue stores the decoded value src in dst, along the lines of what
+// database/sql does for Scan.
+func assignValue(dst reflect.Value, src interface{}) error {
+	if s, ok := dst.Addr().Interface().(sql.Scanner); ok {
+		return s.Scan(src)
+	}
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		p := reflect.New(dst.Type().Elem())
+		if err := assignValue(p.Elem(), src); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	}
+
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(dst.Type()) {
+		dst.Set(sv)
+		return nil
+	}
+
+	var s string
+	switch src := src.(type) {
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	case int64, float64, bool:
+		s = fmt.Sprint(src)
+	default:
+		return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+	}
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(b)
+	default:
+		if dst.Type() == reflect.TypeOf([]byte(nil)) {
+			dst.SetBytes([]byte(s))
+			return nil
+		}
+		return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+	}
+	return nil
+}
+
+// encodeText builds a record literal from a value of ct's Go type.
+func (ct *compositeType) encodeText(v interface{}) ([]byte, error) {
+	return ct.encode(nil, v)
+}
+
+// encode builds a record literal from a value of ct's Go type, formatting
+// Money attributes for the server c is connected to (if not nil).
+func (ct *compositeType) encode(c *libpqConn, v interface{}) ([]byte, error) {
+	sv := reflect.ValueOf(v)
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, attr := range ct.attrs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if attr.field == nil {
+			continue
+		}
+		s, null, err := encodeElement(c, ct.codecs, sv.FieldByIndex(attr.field).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("libpq: attribute %s: %s", attr.name, err)
+		}
+		if null {
+			continue
+		}
+		b.WriteByte('"')
+		for j := 0; j < len(s); j++ {
+			if s[j] == '"' || s[j] == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(s[j])
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte(')')
+	return []byte(b.String()), nil
+}
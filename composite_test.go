@@ -0,0 +1,79 @@
+package libpq_test
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+type testItem struct {
+	ID    int32
+	Name  string
+	Note  *string `db:"comment"`
+	Price float64
+	Skip  string `db:"-"`
+}
+
+func TestComposite(t *testing.T) {
+	setup := getConn(t)
+	defer setup.Close()
+	mustExec(t, setup, "DROP TYPE IF EXISTS test_item")
+	mustExec(t, setup, "CREATE TYPE test_item AS (id int4, name text, comment text, price float8)")
+	defer setup.Exec("DROP TYPE test_item")
+
+	codecs := libpq.NewCodecs()
+	if err := codecs.RegisterComposite(setup, "test_item", reflect.TypeOf(testItem{})); err != nil {
+		t.Fatalf("Failed to register composite type: %s", err)
+	}
+	c := libpq.NewConnector(testDSN())
+	c.Codecs = codecs
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	var item testItem
+	err := db.QueryRow(`select row(1, 'a "quoted", (parenthesized) \ name', null, 2.5)::test_item`).Scan(&item)
+	if err != nil {
+		t.Fatalf("Failed to Scan() composite into a struct: %s", err)
+	}
+	if item.ID != 1 || item.Name != `a "quoted", (parenthesized) \ name` || item.Note != nil || item.Price != 2.5 {
+		t.Errorf("Unexpected composite value %+v", item)
+	}
+
+	note := "note"
+	item.Note = &note
+	var back testItem
+	if err := db.QueryRow("select $1::test_item", item).Scan(&back); err != nil {
+		t.Fatalf("Failed to round-trip composite: %s", err)
+	}
+	if back.Name != item.Name || back.Note == nil || *back.Note != note {
+		t.Errorf("Composite %+v came back as %+v", item, back)
+	}
+
+	var comment string
+	if err := db.QueryRow("select ($1::test_item).comment", item).Scan(&comment); err != nil || comment != note {
+		t.Errorf("Struct not encoded as a record literal: %v (got %q)", err, comment)
+	}
+
+	// without a Go type, attributes come back as a slice
+	untyped := libpq.NewCodecs()
+	if err := untyped.RegisterComposite(setup, "test_item", nil); err != nil {
+		t.Fatalf("Failed to register composite type: %s", err)
+	}
+	c = libpq.NewConnector(testDSN())
+	c.Codecs = untyped
+	udb := sql.OpenDB(c)
+	defer udb.Close()
+	var attrs []interface{}
+	if err := udb.QueryRow("select row(2, 'b', '', 3)::test_item").Scan(&attrs); err != nil {
+		t.Fatalf("Failed to Scan() composite into a slice: %s", err)
+	}
+	if len(attrs) != 4 || attrs[1] != "b" || attrs[2] != "" {
+		t.Errorf("Unexpected composite attributes %#v", attrs)
+	}
+
+	if err := untyped.RegisterComposite(setup, "int4", nil); err == nil {
+		t.Errorf("Expected error registering a non-composite type")
+	}
+}
@@ -0,0 +1,75 @@
+package libpq
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+)
+
+// Connector implements driver.Connector for use with sql.OpenDB, and allows
+// options that cannot be expressed in a connection string to be set. Its
+// fields must not be modified once it has been passed to sql.OpenDB.
+//
+//	c := libpq.NewConnector("dbname=gosqltest sslmode=disable")
+//	c.Reconnect = &libpq.ReconnectPolicy{}
+//	db := sql.OpenDB(c)
+type Connector struct {
+	dsn string
+
+	// If non-nil, connections whose server connection is lost are reset
+	// according to this policy instead of being discarded.
+	Reconnect *ReconnectPolicy
+
+	// Statements (e.g., SET commands) executed on every new connection, and
+	// again whenever a connection is reset.
+	SessionInit []string
+
+	// Codecs for types the driver does not handle itself. If nil, only
+	// DefaultCodecs is used.
+	Codecs *Codecs
+
+	// If non-nil, called when a connection reaches a different server than
+	// the previous connection did (e.g., libpq moved on to the next host of a
+	// multi-host connection string because the first is down).
+	OnFailover func(FailoverEvent)
+
+	mu         sync.Mutex
+	host, port string
+}
+
+// NewConnector returns a Connector for the connection string dsn, which is
+// passed unchanged to PQconnectdb.
+func NewConnector(dsn string) *Connector {
+	return &Connector{dsn: dsn}
+}
+
+// Implement Connector interface.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	conn, err := pqDriver.open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.connector = c
+	conn.reconnect = c.Reconnect
+	conn.sessionInit = c.SessionInit
+	if c.Codecs != nil {
+		conn.codecs = c.Codecs
+	}
+	if err = conn.initSession(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.noteConnected(conn)
+
+	return conn, nil
+}
+
+// Implement Connector interface.
+func (c *Connector) Driver() driver.Driver {
+	return pqDriver
+}
@@ -0,0 +1,45 @@
+package libpq
+
+/*
+#include <stdlib.h>
+#include <libpq-fe.h>
+*/
+import "C"
+import "unsafe"
+
+// ParameterStatus returns the current value of a parameter the server
+// reports to clients (e.g., "server_version", "server_encoding",
+// "client_encoding", "TimeZone", "integer_datetimes",
+// "standard_conforming_strings", "is_superuser", "application_name"), and
+// whether the server reported it at all.
+func (c *libpqConn) ParameterStatus(name string) (string, bool) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	cval := C.PQparameterStatus(c.db, cname)
+	if cval == nil {
+		return "", false
+	}
+	return C.GoString(cval), true
+}
+
+// ServerVersion returns the server version as an integer, e.g. 150004 for
+// 15.4 or 90624 for 9.6.24.
+func (c *libpqConn) ServerVersion() int {
+	return int(C.PQserverVersion(c.db))
+}
+
+// ProtocolVersion returns the frontend/backend protocol version in use.
+func (c *libpqConn) ProtocolVersion() int {
+	return int(C.PQprotocolVersion(c.db))
+}
+
+// BackendPID returns the process ID of the server process handling this
+// connection.
+func (c *libpqConn) BackendPID() int {
+	return int(C.PQbackendPID(c.db))
+}
+
+// SSLInUse reports whether the connection uses SSL.
+func (c *libpqConn) SSLInUse() bool {
+	return C.PQsslInUse(c.db) == 1
+}
@@ -0,0 +1,50 @@
+package libpq_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func TestParameterStatus(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %s", err)
+	}
+	defer conn.Close()
+
+	var pid int64
+	if err = conn.QueryRowContext(context.Background(), "select pg_backend_pid()").Scan(&pid); err != nil {
+		t.Fatalf("Failed to get backend pid: %s", err)
+	}
+
+	err = conn.Raw(func(dc interface{}) error {
+		pc := dc.(libpq.Conn)
+		if pc.BackendPID() != int(pid) {
+			t.Errorf("BackendPID() = %d, expected %d", pc.BackendPID(), pid)
+		}
+		if pc.ServerVersion() < 90000 {
+			t.Errorf("Unexpected server version %d", pc.ServerVersion())
+		}
+		if pc.ProtocolVersion() != 3 {
+			t.Errorf("Unexpected protocol version %d", pc.ProtocolVersion())
+		}
+		if pc.SSLInUse() {
+			t.Errorf("SSL in use despite sslmode=disable")
+		}
+		if enc, ok := pc.ParameterStatus("server_encoding"); !ok || enc == "" {
+			t.Errorf("server_encoding not reported")
+		}
+		if _, ok := pc.ParameterStatus("no_such_parameter"); ok {
+			t.Errorf("Bogus parameter reported")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
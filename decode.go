@@ -0,0 +1,271 @@
+package libpq
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// decode converts the text representation val of a value of type typ into
+// a driver.Value. val may point into a PGresult (see libpqRows.Next), so it
+// is copied for types whose values would keep hold of it.
+func (c *libpqConn) decode(typ oid, val string) (driver.Value, error) {
+	switch typ {
+	case oidBytea:
+		return decodeBytea(val, c.ServerVersion() >= 90000)
+	case oidInt2, oidInt4, oidInt8, oidOid, oidFloat4, oidFloat8, oidBool,
+		oidJSON, oidJSONB, oidXML, oidMoney:
+		return decodeText(typ, val)
+	}
+	return decodeText(typ, strings.Clone(val))
+}
+
+// decodeText is decode for when there is no connection at hand, e.g. for the
+// attributes of a composite value.
+func decodeText(typ oid, val string) (driver.Value, error) {
+	switch typ {
+	case oidInt2, oidInt4, oidInt8, oidOid:
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("libpq: could not parse integer %s: %s", val, err)
+		}
+		return n, nil
+	case oidFloat4, oidFloat8:
+		// ParseFloat understands NaN, Infinity and -Infinity too
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("libpq: could not parse float %s: %s", val, err)
+		}
+		return f, nil
+	case oidBool:
+		return val == "t", nil
+	case oidText, oidVarchar:
+		return val, nil
+	case oidBytea:
+		return decodeBytea(val, true)
+	case oidJSON, oidJSONB, oidXML:
+		return []byte(val), nil
+	case oidMoney:
+		return parseMoney(val)
+	case oidUUID:
+		// returned as text; UUID.Scan parses it
+		return val, nil
+	case oidDate:
+		return parseDate(val)
+	case oidTimestamp:
+		return parseTimestamp(val)
+	case oidTimestampTz:
+		return parseTimestampTz(val)
+	case oidTime:
+		t, err := time.Parse("15:04:05", val)
+		if err != nil {
+			return nil, fmt.Errorf("libpq: could not parse TIME %s: %s", val, err)
+		}
+		return t, nil
+	case oidTimeTz:
+		t, err := time.Parse("15:04:05-07", val)
+		if err != nil {
+			return nil, fmt.Errorf("libpq: could not parse TIME WITH TIME ZONE %s: %s", val, err)
+		}
+		return t, nil
+	}
+	return val, nil
+}
+
+func parseDate(val string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02", val)
+	if err != nil {
+		return t, fmt.Errorf("libpq: could not parse DATE %s: %s", val, err)
+	}
+	return t, nil
+}
+
+func parseTimestamp(val string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02 15:04:05", val)
+	if err != nil {
+		return t, fmt.Errorf("libpq: could not parse TIMESTAMP %s: %s", val, err)
+	}
+	return t, nil
+}
+
+func parseTimestampTz(val string) (time.Time, error) {
+	var t time.Time
+	var err error
+	for _, timeFormat := range []string{
+		"2006-01-02 15:04:05-07",
+		"2006-01-02 15:04:05.000000-07",
+		"2006-01-02 15:04:05-07:00",
+		"2006-01-02 15:04:05.000000-07:00",
+	} {
+		t, err = time.Parse(timeFormat, val)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return t, fmt.Errorf("libpq: could not parse TIMESTAMP WITH TIME ZONE %s: %s", val, err)
+	}
+	return t, nil
+}
+
+// parseDateOrTimestamp parses val as whichever of DATE, TIMESTAMP or
+// TIMESTAMP WITH TIME ZONE it looks like, for contexts where the type is not
+// known (e.g., range bounds).
+func parseDateOrTimestamp(val string) (time.Time, error) {
+	switch {
+	case len(val) == len("2006-01-02"):
+		return parseDate(val)
+	case strings.LastIndexAny(val, "+-") > len("2006-01-02"):
+		return parseTimestampTz(val)
+	}
+	return parseTimestamp(val)
+}
+
+// decodeBinary converts the binary representation src of a value of type typ
+// into a driver.Value. Types without a binary decoder are returned as raw
+// bytes.
+func decodeBinary(typ oid, src []byte) (driver.Value, error) {
+	switch typ {
+	case oidBool:
+		if len(src) != 1 {
+			return nil, errors.New("libpq: invalid binary bool")
+		}
+		return src[0] != 0, nil
+	case oidInt2:
+		if len(src) != 2 {
+			return nil, errors.New("libpq: invalid binary int2")
+		}
+		return int64(int16(binary.BigEndian.Uint16(src))), nil
+	case oidInt4:
+		if len(src) != 4 {
+			return nil, errors.New("libpq: invalid binary int4")
+		}
+		return int64(int32(binary.BigEndian.Uint32(src))), nil
+	case oidInt8:
+		if len(src) != 8 {
+			return nil, errors.New("libpq: invalid binary int8")
+		}
+		return int64(binary.BigEndian.Uint64(src)), nil
+	case oidFloat4:
+		if len(src) != 4 {
+			return nil, errors.New("libpq: invalid binary float4")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(src))), nil
+	case oidFloat8:
+		if len(src) != 8 {
+			return nil, errors.New("libpq: invalid binary float8")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(src)), nil
+	case oidText, oidVarchar, oidBpchar:
+		return string(src), nil
+	case oidJSONB:
+		// binary jsonb is a version byte followed by the JSON text
+		if len(src) == 0 || src[0] != 1 {
+			return nil, errors.New("libpq: unsupported binary jsonb version")
+		}
+		return src[1:], nil
+	case oidUUID:
+		if len(src) != 16 {
+			return nil, errors.New("libpq: invalid binary uuid")
+		}
+		var u UUID
+		copy(u[:], src)
+		return u.String(), nil
+	case oidInterval:
+		if len(src) != 16 {
+			return nil, errors.New("libpq: invalid binary interval")
+		}
+		return Interval{
+			Microseconds: int64(binary.BigEndian.Uint64(src)),
+			Days:         int32(binary.BigEndian.Uint32(src[8:])),
+			Months:       int32(binary.BigEndian.Uint32(src[12:])),
+		}.String(), nil
+	case oidInet, oidCIDR:
+		// family, bits, is_cidr, address length, address
+		if len(src) < 4 || len(src) != 4+int(src[3]) {
+			return nil, errors.New("libpq: invalid binary inet")
+		}
+		addr, ok := netip.AddrFromSlice(src[4:])
+		if !ok {
+			return nil, errors.New("libpq: invalid binary inet")
+		}
+		if typ == oidInet && int(src[1]) == addr.BitLen() {
+			return addr.String(), nil
+		}
+		return netip.PrefixFrom(addr, int(src[1])).String(), nil
+	case oidMacaddr, oidMacaddr8:
+		return net.HardwareAddr(src).String(), nil
+	case oidPoint, oidLine, oidLseg, oidBox, oidPath, oidPolygon, oidCircle:
+		return decodeGeometry(typ, src)
+	case oidBit, oidVarbit:
+		return decodeBitString(src)
+	case oidMoney:
+		if len(src) != 8 {
+			return nil, errors.New("libpq: invalid binary money")
+		}
+		return int64(binary.BigEndian.Uint64(src)), nil
+	}
+	return src, nil
+}
+
+// Servers before 9.0 only send bytea in escape format; later ones use hex
+// unless bytea_output has been set to 'escape'. hexFormat says whether the
+// server might have used hex.
+func decodeBytea(val string, hexFormat bool) ([]byte, error) {
+	if hexFormat && strings.HasPrefix(val, `\x`) {
+		// hex.Decode only reads from its source, so val need not be copied
+		// to a []byte first
+		src := unsafe.Slice(unsafe.StringData(val[2:]), len(val)-2)
+		b := make([]byte, hex.DecodedLen(len(src)))
+		if _, err := hex.Decode(b, src); err != nil {
+			return nil, fmt.Errorf("libpq: could not decode hex string: %s", err)
+		}
+		return b, nil
+	}
+
+	b := make([]byte, 0, len(val))
+	for i := 0; i < len(val); i++ {
+		if val[i] != '\\' {
+			b = append(b, val[i])
+			continue
+		}
+		switch {
+		case i+1 < len(val) && val[i+1] == '\\':
+			b = append(b, '\\')
+			i++
+		case i+3 < len(val):
+			n, err := strconv.ParseUint(val[i+1:i+4], 8, 8)
+			if err != nil {
+				return nil, errors.New("libpq: invalid byte string format")
+			}
+			b = append(b, byte(n))
+			i += 3
+		default:
+			return nil, errors.New("libpq: invalid byte string format")
+		}
+	}
+	return b, nil
+}
+
+// srcText returns the text of a value handed to a Scan method, with null
+// set for NULL. into names the type being scanned into, for errors.
+func srcText(src interface{}, into string) (s string, null bool, err error) {
+	switch src := src.(type) {
+	case string:
+		return src, false, nil
+	case []byte:
+		return string(src), false, nil
+	case nil:
+		return "", true, nil
+	}
+	return "", false, fmt.Errorf("libpq: cannot scan %T into %s", src, into)
+}
@@ -6,16 +6,13 @@ package libpq
 */
 import "C"
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
-	"encoding/hex"
 	"errors"
-	"fmt"
 	"io"
 	"strconv"
-	"strings"
 	"sync"
-	"time"
 	"unsafe"
 )
 
@@ -28,29 +25,35 @@ var (
 
 	// Error returned by Open() if we could not determine Postgres OIDs.
 	ErrFetchingOids = errors.New("libpq: Could not fetch base datatype OIDs")
-)
 
-type pqoid struct {
-	Bytea       int
-	Date        int
-	Timestamp   int
-	TimestampTz int
-	Time        int
-	TimeTz      int
-}
+	// Error returned by BeginTx() for isolation levels Postgres does not have.
+	ErrIsolationLevel = errors.New("libpq: unsupported isolation level")
+)
 
 type libpqDriver struct {
 	sync.Mutex
-	oids map[string]*pqoid
+
+	// type registries, keyed by server identity (see types.go)
+	types map[string]*typeRegistry
 }
 
+var pqDriver = &libpqDriver{types: make(map[string]*typeRegistry)}
+
 func init() {
-	go handleArgpool()
-	sql.Register("libpq", &libpqDriver{oids: make(map[string]*pqoid)})
+	sql.Register("libpq", pqDriver)
 }
 
 // dsn is passed directly to PQconnectdb
 func (d *libpqDriver) Open(dsn string) (driver.Conn, error) {
+	return d.open(dsn)
+}
+
+// Implement DriverContext interface.
+func (d *libpqDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	return NewConnector(dsn), nil
+}
+
+func (d *libpqDriver) open(dsn string) (*libpqConn, error) {
 	if C.PQisthreadsafe() != 1 {
 		return nil, ErrThreadSafety
 	}
@@ -64,79 +67,81 @@ func (d *libpqDriver) Open(dsn string) (driver.Conn, error) {
 		return nil, errors.New("libpq: connection error " + C.GoString(C.PQerrorMessage(db)))
 	}
 
-	oids, err := d.getOids(db, dsn)
-	if err != nil {
-		defer C.PQfinish(db)
-		return nil, ErrFetchingOids
-	}
-
-	redirectOutput(db)
-
-	return &libpqConn{
+	c := &libpqConn{
 		db:        db,
-		oids:      oids,
+		codecs:    DefaultCodecs,
 		stmtCache: make(map[string]driver.Stmt),
 		stmtNum:   0,
-	}, nil
-}
-
-func (d *libpqDriver) getOids(db *C.PGconn, dsn string) (*pqoid, error) {
-	var err error
-	d.Lock()
-	defer d.Unlock()
-
-	// check cache
-	if oids, ok := d.oids[dsn]; ok {
-		return oids, nil
-	}
-
-	// not in cache - query the database
-	oids := &pqoid{}
-	names := []struct {
-		kind string
-		dest *int
-	}{
-		{"'bytea'", &oids.Bytea},
-		{"'date'", &oids.Date},
-		{"'timestamp'", &oids.Timestamp},
-		{"'timestamp with time zone'", &oids.TimestampTz},
-		{"'time'", &oids.Time},
-		{"'time with time zone'", &oids.TimeTz},
-	}
-
-	// fetch all the OIDs we care about
-	for _, n := range names {
-		ccmd := C.CString("SELECT " + n.kind + "::regtype::oid")
-		defer C.free(unsafe.Pointer(ccmd))
-		cres := C.PQexec(db, ccmd)
-		defer C.PQclear(cres)
-		if err := resultError(cres); err != nil {
-			return nil, err
-		}
-		sval := C.GoString(C.PQgetvalue(cres, 0, 0))
-		*n.dest, err = strconv.Atoi(sval)
-		if err != nil {
-			return nil, ErrFetchingOids
-		}
+	}
+	if err := c.loadTypes(); err != nil {
+		defer C.PQfinish(db)
+		return nil, ErrFetchingOids
 	}
 
-	// save in cache for next time
-	d.oids[dsn] = oids
+	redirectOutput(db)
 
-	return oids, nil
+	return c, nil
 }
 
 type libpqConn struct {
 	db        *C.PGconn
-	oids      *pqoid
+	types     *typeRegistry
+	codecs    *Codecs
 	stmtCache map[string]driver.Stmt
 	stmtNum   int
+	money     *moneyFormat // see money.go; nil until needed
+	args      *cArgs       // reused for each query's parameters (see cargs.go)
+
+	// nil if opened directly through Open()
+	connector *Connector
+
+	// session state restored by reset() (see reconnect.go)
+	reconnect   *ReconnectPolicy
+	sessionInit []string
+	listens     []string
+	inTx        bool
+	resetting   bool
+
+	// set when the connection is left in an unknown state (see batch.go),
+	// so that database/sql discards it
+	broken bool
 }
 
 func (c *libpqConn) Begin() (driver.Tx, error) {
-	if _, err := c.exec("BEGIN", false); err != nil {
+	return c.begin("BEGIN")
+}
+
+// Implement ConnBeginTx interface.
+func (c *libpqConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+
+	cmd := "BEGIN"
+	switch sql.IsolationLevel(opts.Isolation) {
+	case sql.LevelDefault:
+	case sql.LevelReadUncommitted:
+		cmd += " ISOLATION LEVEL READ UNCOMMITTED"
+	case sql.LevelReadCommitted:
+		cmd += " ISOLATION LEVEL READ COMMITTED"
+	case sql.LevelRepeatableRead:
+		cmd += " ISOLATION LEVEL REPEATABLE READ"
+	case sql.LevelSerializable:
+		cmd += " ISOLATION LEVEL SERIALIZABLE"
+	default:
+		return nil, ErrIsolationLevel
+	}
+	if opts.ReadOnly {
+		cmd += " READ ONLY"
+	}
+	return c.begin(cmd)
+}
+
+func (c *libpqConn) begin(cmd string) (driver.Tx, error) {
+	if _, err := c.exec(cmd, false); err != nil {
+		return nil, err
+	}
+	c.inTx = true
 	return &libpqTx{c}, nil
 }
 
@@ -145,11 +150,13 @@ type libpqTx struct {
 }
 
 func (tx *libpqTx) Commit() error {
+	tx.c.inTx = false
 	_, err := tx.c.exec("COMMIT", false)
 	return err
 }
 
 func (tx *libpqTx) Rollback() error {
+	tx.c.inTx = false
 	_, err := tx.c.exec("ROLLBACK", false)
 	return err
 }
@@ -162,14 +169,26 @@ func (c *libpqConn) Close() error {
 			C.free(unsafe.Pointer(stmt.name))
 		}
 	}
+	if c.args != nil {
+		c.args.release()
+	}
 	return nil
 }
 
+// Implement Validator interface.
+func (c *libpqConn) IsValid() bool {
+	return !c.broken
+}
+
 // Execute a query, possibly getting a result object (unless the caller doesn't
 // want it, as in the case of BEGIN/COMMIT/ROLLBACK).
 // the caller doesn't care about that (e.g., Begin(), Commit(), Rollback()).
 //func (c *libpqConn) exec(cmd string, res *libpqResult) error {
 func (c *libpqConn) exec(cmd string, wantResult bool) (driver.Result, error) {
+	if err := c.ensureConn(); err != nil {
+		return nil, err
+	}
+
 	ccmd := C.CString(cmd)
 	defer C.free(unsafe.Pointer(ccmd))
 	cres := C.PQexec(c.db, ccmd)
@@ -192,18 +211,22 @@ func (c *libpqConn) exec(cmd string, wantResult bool) (driver.Result, error) {
 
 // Execute a query with 1 or more parameters.
 func (c *libpqConn) execParams(cmd string, args []driver.Value) (driver.Result, error) {
+	if err := c.ensureConn(); err != nil {
+		return nil, err
+	}
+
 	// convert args into C array-of-strings
-	cargs, err := buildCArgs(args)
+	cargs, err := c.buildCArgs(args)
 	if err != nil {
 		return nil, err
 	}
-	defer returnCharArrayToPool(len(args), cargs)
+	defer cargs.free()
 
 	ccmd := C.CString(cmd)
 	defer C.free(unsafe.Pointer(ccmd))
 
 	// execute
-	cres := C.PQexecParams(c.db, ccmd, C.int(len(args)), nil, cargs, nil, nil, 0)
+	cres := C.PQexecParams(c.db, ccmd, C.int(len(args)), cargs.types, cargs.values, cargs.lengths, cargs.formats, 0)
 	defer C.PQclear(cres)
 	if err = resultError(cres); err != nil {
 		return nil, err
@@ -228,17 +251,59 @@ func (c *libpqConn) Exec(query string, args []driver.Value) (driver.Result, erro
 }
 
 func (c *libpqConn) Prepare(query string) (driver.Stmt, error) {
+	return c.prepareCached(query, query, nil)
+}
+
+// prepareNamed prepares query with its named placeholders rewritten as
+// positional ones (see named.go). The rewrite is cached apart from query
+// itself, which Prepare sends as written.
+func (c *libpqConn) prepareNamed(query string) (*libpqStmt, error) {
+	key := namedKeyPrefix + query
+	if cached, ok := c.stmtCache[key]; ok {
+		return cached.(*libpqStmt), nil
+	}
+	pquery, names := rewriteNamed(query, c.standardConformingStrings())
+	stmt, err := c.prepareCached(key, pquery, names)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.(*libpqStmt), nil
+}
+
+// prepareCached prepares query, taking the named parameters in names if any,
+// or returns the statement cached under key.
+func (c *libpqConn) prepareCached(key, query string, names []string) (driver.Stmt, error) {
 	// check our connection's query cache to see if we've already prepared this
-	cached, ok := c.stmtCache[query]
+	cached, ok := c.stmtCache[key]
 	if ok {
 		return cached, nil
 	}
 
+	if err := c.ensureConn(); err != nil {
+		return nil, err
+	}
+
 	// create unique statement name
 	// NOTE: do NOT free cname here because it is cached in c.stmtCache;
 	//       all cached statement names are freed in c.Close()
 	cname := C.CString(strconv.Itoa(c.stmtNum))
 	c.stmtNum++
+
+	nparams, err := c.prepare(cname, query)
+	if err != nil {
+		C.free(unsafe.Pointer(cname))
+		return nil, err
+	}
+
+	// save statement in cache
+	stmt := &libpqStmt{c: c, name: cname, query: query, names: names, nparams: nparams}
+	c.stmtCache[key] = stmt
+	return stmt, nil
+}
+
+// Prepare query under the statement name cname, returning the number of
+// parameters it takes.
+func (c *libpqConn) prepare(cname *C.char, query string) (int, error) {
 	cquery := C.CString(query)
 	defer C.free(unsafe.Pointer(cquery))
 
@@ -246,21 +311,16 @@ func (c *libpqConn) Prepare(query string) (driver.Stmt, error) {
 	cres := C.PQprepare(c.db, cname, cquery, 0, nil)
 	defer C.PQclear(cres)
 	if err := resultError(cres); err != nil {
-		return nil, err
+		return 0, err
 	}
 
 	// get number of parameters in this query
 	cinfo := C.PQdescribePrepared(c.db, cname)
 	defer C.PQclear(cinfo)
 	if err := resultError(cinfo); err != nil {
-		return nil, err
+		return 0, err
 	}
-	nparams := int(C.PQnparams(cinfo))
-
-	// save statement in cache
-	stmt := &libpqStmt{c: c, name: cname, query: query, nparams: nparams}
-	c.stmtCache[query] = stmt
-	return stmt, nil
+	return int(C.PQnparams(cinfo)), nil
 }
 
 type libpqStmt struct {
@@ -269,6 +329,7 @@ type libpqStmt struct {
 	query   string
 	cquery  *C.char
 	nparams int
+	names   []string // of the parameters, if the query used named placeholders
 }
 
 func (s *libpqStmt) Close() error {
@@ -284,6 +345,10 @@ func (s *libpqStmt) NumInput() int {
 }
 
 func (s *libpqStmt) exec(args []driver.Value) (*C.PGresult, error) {
+	if err := s.c.ensureConn(); err != nil {
+		return nil, err
+	}
+
 	// if we have no arguments, use plain exec instead of more complicated PQexecPrepared
 	if len(args) == 0 {
 		if s.cquery == nil {
@@ -298,14 +363,14 @@ func (s *libpqStmt) exec(args []driver.Value) (*C.PGresult, error) {
 	}
 
 	// convert args into C array-of-strings
-	cargs, err := buildCArgs(args)
+	cargs, err := s.c.buildCArgs(args)
 	if err != nil {
 		return nil, err
 	}
-	defer returnCharArrayToPool(len(args), cargs)
+	defer cargs.free()
 
 	// execute
-	cres := C.PQexecPrepared(s.c.db, s.name, C.int(len(args)), cargs, nil, nil, 0)
+	cres := C.PQexecPrepared(s.c.db, s.name, C.int(len(args)), cargs.values, cargs.lengths, cargs.formats, 0)
 	if err = resultError(cres); err != nil {
 		C.PQclear(cres)
 		return nil, err
@@ -339,6 +404,7 @@ func (s *libpqStmt) Query(args []driver.Value) (driver.Rows, error) {
 	// check to see if this was a "LISTEN"
 	if C.GoString(C.PQcmdStatus(cres)) == "LISTEN" {
 		C.PQclear(cres)
+		s.c.listens = append(s.c.listens, s.query)
 		return &libpqListenRows{s.c}, nil
 	}
 
@@ -359,6 +425,9 @@ type libpqRows struct {
 	nrows   int
 	currRow int
 	cols    []string
+
+	// registered codec (or nil) for each column, filled in by the first Next()
+	codecs []*Codec
 }
 
 func resultError(res *C.PGresult) error {
@@ -400,6 +469,14 @@ func (r *libpqRows) Next(dest []driver.Value) error {
 	currRow := C.int(r.currRow)
 	r.currRow++
 
+	if r.codecs == nil {
+		r.codecs = make([]*Codec, r.ncols)
+		for i := range r.codecs {
+			typ := oid(C.PQftype(r.res, C.int(i)))
+			r.codecs[i] = r.s.c.codecs.forColumn(typ, r.s.c.typeByOid(typ))
+		}
+	}
+
 	for i := 0; i < len(dest); i++ {
 		ci := C.int(i)
 
@@ -410,53 +487,26 @@ func (r *libpqRows) Next(dest []driver.Value) error {
 		}
 
 		var err error
-		val := C.GoString(C.PQgetvalue(r.res, currRow, ci))
-		switch vtype := int(C.PQftype(r.res, ci)); vtype {
-		case r.s.c.oids.Bytea:
-			if !strings.HasPrefix(val, `\x`) {
-				return errors.New("libpq: invalid byte string format")
-			}
-			dest[i], err = hex.DecodeString(val[2:])
-			if err != nil {
-				return errors.New(fmt.Sprint("libpq: could not decode hex string: %s", err))
-			}
-		case r.s.c.oids.Date:
-			dest[i], err = time.Parse("2006-01-02", val)
-			if err != nil {
-				return errors.New(fmt.Sprint("libpq: could not parse DATE %s: %s", val, err))
+		format := int(C.PQfformat(r.res, ci))
+		if codec := r.codecs[i]; codec != nil || format == 1 {
+			src := C.GoBytes(unsafe.Pointer(C.PQgetvalue(r.res, currRow, ci)), C.PQgetlength(r.res, currRow, ci))
+			if codec != nil {
+				dest[i], err = decodeWithCodec(codec, format, src)
+			} else {
+				dest[i], err = decodeBinary(oid(C.PQftype(r.res, ci)), src)
 			}
-		case r.s.c.oids.Timestamp:
-			dest[i], err = time.Parse("2006-01-02 15:04:05", val)
 			if err != nil {
-				return errors.New(fmt.Sprint("libpq: could not parse TIMESTAMP %s: %s", val, err))
+				return err
 			}
-		case r.s.c.oids.TimestampTz:
-			for _, timeFormat := range []string{
-				"2006-01-02 15:04:05-07",
-				"2006-01-02 15:04:05.000000-07",
-				"2006-01-02 15:04:05-07:00",
-				"2006-01-02 15:04:05.000000-07:00",
-			} {
-				dest[i], err = time.Parse(timeFormat, val)
-				if err == nil {
-					break
-				}
-			}
-			if err != nil {
-				return errors.New(fmt.Sprint("libpq: could not parse TIMESTAMP WITH TIME ZONE %s: %s", val, err))
-			}
-		case r.s.c.oids.Time:
-			dest[i], err = time.Parse("15:04:05", val)
-			if err != nil {
-				return errors.New(fmt.Sprint("libpq: could not parse TIME %s: %s", val, err))
-			}
-		case r.s.c.oids.TimeTz:
-			dest[i], err = time.Parse("15:04:05-07", val)
-			if err != nil {
-				return errors.New(fmt.Sprint("libpq: could not parse TIME WITH TIME ZONE %s: %s", val, err))
-			}
-		default:
-			dest[i] = val
+			continue
+		}
+
+		// val points into the result, which lives until Close; decode copies
+		// whatever it keeps, so the values in dest belong to the caller
+		val := unsafe.String((*byte)(unsafe.Pointer(C.PQgetvalue(r.res, currRow, ci))), C.PQgetlength(r.res, currRow, ci))
+		dest[i], err = r.s.c.decode(oid(C.PQftype(r.res, ci)), val)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -1,21 +1,27 @@
 package libpq_test
 
 import (
+	"bytes"
 	"database/sql"
 	"fmt"
 	_ "github.com/jgallagher/go-libpq"
+	"math"
 	"os"
 	"testing"
 	"time"
 )
 
-func getConn(t *testing.T) *sql.DB {
+func testDSN() string {
 	user := os.Getenv("GOSQLTEST_PQ_USER")
 	if user == "" {
 		user = os.Getenv("USER")
 	}
 	dbName := "gosqltest"
-	db, err := sql.Open("libpq", fmt.Sprintf("user=%s password=gosqltest dbname=%s sslmode=disable", user, dbName))
+	return fmt.Sprintf("user=%s password=gosqltest dbname=%s sslmode=disable", user, dbName)
+}
+
+func getConn(t testing.TB) *sql.DB {
+	db, err := sql.Open("libpq", testDSN())
 	if err != nil {
 		t.Fatalf("Failed to open database: ", err)
 	}
@@ -58,6 +64,36 @@ func TestFloat(t *testing.T) {
 	}
 }
 
+func TestSpecialFloats(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+	var nan, inf, ninf float64
+	err := db.QueryRow("select 'NaN'::float8, 'Infinity'::float4, $1::float8", math.Inf(-1)).Scan(&nan, &inf, &ninf)
+	if err != nil {
+		t.Fatalf("Failed to Scan() special floats: %s", err)
+	}
+	if !math.IsNaN(nan) || !math.IsInf(inf, 1) || !math.IsInf(ninf, -1) {
+		t.Fatalf("Unexpected special floats %v, %v, %v", nan, inf, ninf)
+	}
+}
+
+func TestTypedValues(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+	var i2, i4, i8, o, f4, f8, b, s, vc interface{}
+	err := db.QueryRow(`select 1::int2, 2::int4, 3::int8, 4::oid, 1.5::float4,
+		2.5::float8, true, 'text'::text, 'varchar'::varchar`).Scan(&i2, &i4, &i8, &o, &f4, &f8, &b, &s, &vc)
+	if err != nil {
+		t.Fatalf("Failed to Scan() into interface{}: %s", err)
+	}
+	expect := []interface{}{int64(1), int64(2), int64(3), int64(4), 1.5, 2.5, true, "text", "varchar"}
+	for i, v := range []interface{}{i2, i4, i8, o, f4, f8, b, s, vc} {
+		if v != expect[i] {
+			t.Errorf("Column %d: got %#v, expected %#v", i+1, v, expect[i])
+		}
+	}
+}
+
 func TestByteArray(t *testing.T) {
 	db := getConn(t)
 	defer db.Close()
@@ -76,6 +112,39 @@ func TestByteArray(t *testing.T) {
 	}
 }
 
+func TestByteaEscapeFormat(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	mustExec(t, db, "SET bytea_output = 'escape'")
+	var val []byte
+	err := db.QueryRow(`select E'\\001ab\\\\\\377'::bytea`).Scan(&val)
+	if err != nil {
+		t.Fatalf("Failed to Scan() an escape-format []byte: %s", err)
+	}
+	if expect := []byte{1, 'a', 'b', '\\', 0xff}; !bytes.Equal(val, expect) {
+		t.Fatalf("Incorrect []byte (expected %v, got %v)", expect, val)
+	}
+}
+
+func TestByteArrayNul(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+	in := []byte{0, 'a', 0, 0, 'b', 0}
+	var out, lit []byte
+	err := db.QueryRow(`select $1::bytea, '\x00610000620000'::bytea`, in).Scan(&out, &lit)
+	if err != nil {
+		t.Fatalf("Failed to Scan() a []byte with NUL bytes: %s", err)
+	}
+	if string(out) != string(in) {
+		t.Errorf("Got %v, expected %v", out, in)
+	}
+	if string(lit) != "\x00a\x00\x00b\x00\x00" {
+		t.Errorf("Got %v for bytea literal", lit)
+	}
+}
+
 func TestString(t *testing.T) {
 	db := getConn(t)
 	defer db.Close()
@@ -127,6 +196,56 @@ func TestNull(t *testing.T) {
 	}
 }
 
+func TestNullParams(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	mustExec(t, db, "DROP TABLE IF EXISTS test")
+	mustExec(t, db, "CREATE TABLE test (s text, i int, b bytea, t timestamp)")
+	defer db.Exec("DROP TABLE test")
+
+	var nilPtr *string
+	for _, args := range [][]interface{}{
+		{nil, nil, nil, nil},
+		{nilPtr, sql.NullInt64{}, []byte(nil), sql.NullTime{}},
+	} {
+		if _, err := db.Exec("insert into test values ($1, $2, $3, $4)", args...); err != nil {
+			t.Fatalf("Failed to Exec() with NULL parameters %v: %s", args, err)
+		}
+	}
+
+	stmt, err := db.Prepare("insert into test values ($1, $2, $3, $4)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := stmt.Exec(nil, nil, nil, nil); err != nil {
+			t.Fatalf("Failed to Exec() a prepared statement with NULLs: %s", err)
+		}
+	}
+
+	var rows, nulls int
+	err = db.QueryRow(`select count(*), count(*) filter
+		(where s is null and i is null and b is null and t is null) from test`).Scan(&rows, &nulls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 4 || nulls != 4 {
+		t.Errorf("Expected 4 rows of NULLs, got %d of %d", nulls, rows)
+	}
+
+	type attrs map[string]string
+	for _, v := range []interface{}{
+		nil, []byte(nil), map[string]string(nil), map[string]*string(nil), attrs(nil),
+	} {
+		var isNull bool
+		if err := db.QueryRow("select $1::text is null", v).Scan(&isNull); err != nil || !isNull {
+			t.Errorf("nil %T parameter not sent as NULL: %v", v, err)
+		}
+	}
+}
+
 func TestTimestampWithTimeZone(t *testing.T) {
 	db := getConn(t)
 	defer db.Close()
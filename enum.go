@@ -0,0 +1,81 @@
+package libpq
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// RegisterEnum registers codecs mapping the enum type with the given
+// (possibly schema-qualified) name to goType, whose underlying type must be
+// string, and the enum's array type to a slice of goType. Parameters of
+// these types are checked against the enum's labels (from pg_enum) before
+// being sent, rather than leaving the server to reject them.
+func (r *Codecs) RegisterEnum(name string, goType reflect.Type) error {
+	if goType.Kind() != reflect.String {
+		return fmt.Errorf("libpq: cannot map enum %s to non-string type %s", name, goType)
+	}
+
+	r.RegisterType(name, &Codec{
+		DecodeText: func(src []byte) (interface{}, error) {
+			return reflect.ValueOf(string(src)).Convert(goType).Interface(), nil
+		},
+		EncodeText: func(v interface{}) ([]byte, error) {
+			return []byte(reflect.ValueOf(v).String()), nil
+		},
+		GoTypes: []reflect.Type{goType},
+	})
+
+	sliceType := reflect.SliceOf(goType)
+	r.registerArrayOf(name, &Codec{
+		DecodeText: func(src []byte) (interface{}, error) {
+			elems, err := parseArray(string(src), ',')
+			if err != nil {
+				return nil, err
+			}
+			arr := reflect.MakeSlice(sliceType, len(elems), len(elems))
+			for i, elem := range elems {
+				if elem == nil {
+					return nil, fmt.Errorf("libpq: cannot decode NULL element into %s", goType)
+				}
+				arr.Index(i).SetString(*elem)
+			}
+			return arr.Interface(), nil
+		},
+		EncodeText: func(v interface{}) ([]byte, error) {
+			arr := reflect.ValueOf(v)
+			elems := make([]*string, arr.Len())
+			for i := range elems {
+				s := arr.Index(i).String()
+				elems[i] = &s
+			}
+			return []byte(formatArray(elems, ',')), nil
+		},
+		GoTypes: []reflect.Type{sliceType},
+	})
+	return nil
+}
+
+// checkEnum returns an error if text, a parameter of type typ, is not a
+// valid label (or array of labels) of an enum type.
+func (c *libpqConn) checkEnum(typ oid, text string) error {
+	t := c.types.byOid[typ]
+	if t == nil {
+		return nil
+	}
+	labels := []*string{&text}
+	if t.kind != 'e' {
+		if t = c.types.byOid[t.elem]; t == nil || t.kind != 'e' {
+			return nil
+		}
+		var err error
+		if labels, err = parseArray(text, ','); err != nil {
+			return err
+		}
+	}
+	for _, l := range labels {
+		if l != nil && !c.validEnumLabel(t, *l) {
+			return fmt.Errorf("libpq: invalid input value %q for enum %s", *l, t.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package libpq_test
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+type testColor string
+
+func TestEnum(t *testing.T) {
+	setup := getConn(t)
+	defer setup.Close()
+	mustExec(t, setup, "DROP TYPE IF EXISTS test_color")
+	mustExec(t, setup, "CREATE TYPE test_color AS ENUM ('red', 'green')")
+	defer setup.Exec("DROP TYPE test_color")
+
+	codecs := libpq.NewCodecs()
+	if err := codecs.RegisterEnum("test_color", reflect.TypeOf(testColor(""))); err != nil {
+		t.Fatalf("Failed to register enum: %s", err)
+	}
+	if err := codecs.RegisterEnum("test_color", reflect.TypeOf(0)); err == nil {
+		t.Errorf("Expected error mapping an enum to an int")
+	}
+	c := libpq.NewConnector(testDSN())
+	c.Codecs = codecs
+	db := sql.OpenDB(c)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var color testColor
+	if err := db.QueryRow("select $1::test_color", testColor("green")).Scan(&color); err != nil {
+		t.Fatalf("Failed to round-trip enum: %s", err)
+	}
+	if color != "green" {
+		t.Errorf("Unexpected enum value %q", color)
+	}
+
+	_, err := db.Exec("select $1", testColor("blue"))
+	if err == nil || !strings.Contains(err.Error(), `invalid input value "blue" for enum test_color`) {
+		t.Errorf("Invalid label not rejected client-side: %v", err)
+	}
+
+	var colors []testColor
+	if err := db.QueryRow("select $1::test_color[]", []testColor{"red", "green"}).Scan(&colors); err != nil {
+		t.Fatalf("Failed to round-trip enum array: %s", err)
+	}
+	if len(colors) != 2 || colors[0] != "red" || colors[1] != "green" {
+		t.Errorf("Unexpected enum array %v", colors)
+	}
+	if _, err := db.Exec("select $1", []testColor{"red", "blue"}); err == nil {
+		t.Errorf("Invalid label in array not rejected")
+	}
+
+	var isNull bool
+	if err := db.QueryRow("select $1::test_color[] is null", []testColor(nil)).Scan(&isNull); err != nil || !isNull {
+		t.Errorf("nil enum slice not sent as NULL: %v", err)
+	}
+
+	// labels added after the connection loaded its types
+	mustExec(t, setup, "ALTER TYPE test_color ADD VALUE 'blue'")
+	if err := db.QueryRow("select $1::test_color", testColor("blue")).Scan(&color); err != nil || color != "blue" {
+		t.Errorf("New label not accepted: %v (got %q)", err, color)
+	}
+}
+
+func TestEnumLongName(t *testing.T) {
+	// the array type of a 63 byte name isn't "_" and the name, which would
+	// be cut to 63 bytes
+	const name = "test_color_with_a_name_long_enough_that_its_array_type_is_cut_x"
+	setup := getConn(t)
+	defer setup.Close()
+	mustExec(t, setup, "DROP TYPE IF EXISTS "+name)
+	mustExec(t, setup, "CREATE TYPE "+name+" AS ENUM ('red', 'green')")
+	defer setup.Exec("DROP TYPE " + name)
+
+	codecs := libpq.NewCodecs()
+	if err := codecs.RegisterEnum(name, reflect.TypeOf(testColor(""))); err != nil {
+		t.Fatalf("Failed to register enum: %s", err)
+	}
+	c := libpq.NewConnector(testDSN())
+	c.Codecs = codecs
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	var colors []testColor
+	if err := db.QueryRow("select $1::"+name+"[]", []testColor{"red", "green"}).Scan(&colors); err != nil {
+		t.Fatalf("Failed to round-trip enum array: %s", err)
+	}
+	if len(colors) != 2 || colors[0] != "red" || colors[1] != "green" {
+		t.Errorf("Unexpected enum array %v", colors)
+	}
+}
@@ -0,0 +1,7 @@
+package libpq
+
+// TypeRegistry returns the type registry of the driver connection dc, so
+// tests can tell which connections share one.
+func TypeRegistry(dc interface{}) interface{} {
+	return dc.(*libpqConn).types
+}
@@ -0,0 +1,124 @@
+package libpq
+
+/*
+#include <libpq-fe.h>
+*/
+import "C"
+import "database/sql/driver"
+
+// Conn is implemented by the driver connections this package returns. Use
+// sql.Conn.Raw to get at one:
+//
+//	conn, _ := db.Conn(ctx)
+//	conn.Raw(func(dc interface{}) error {
+//		host := dc.(libpq.Conn).Host()
+//		// ...
+//	})
+type Conn interface {
+	driver.Conn
+
+	// Host and port of the server the connection reached. With a multi-host
+	// connection string, this is the one libpq selected.
+	Host() string
+	Port() string
+
+	// Whether the server is currently a primary or a hot standby.
+	ServerRole() (ServerRole, error)
+
+	// Server parameters and connection details; see conninfo.go.
+	ParameterStatus(name string) (string, bool)
+	ServerVersion() int
+	ProtocolVersion() int
+	BackendPID() int
+	SSLInUse() bool
+
+	// Send queries in pipeline mode; see batch.go.
+	SendBatch(b *Batch) ([]BatchResult, error)
+}
+
+var _ Conn = (*libpqConn)(nil)
+
+// ServerRole is the replication role of a server.
+type ServerRole int
+
+const (
+	RoleUnknown ServerRole = iota
+	RolePrimary
+	RoleStandby
+)
+
+func (r ServerRole) String() string {
+	switch r {
+	case RolePrimary:
+		return "primary"
+	case RoleStandby:
+		return "standby"
+	}
+	return "unknown"
+}
+
+// FailoverEvent is passed to OnFailover callbacks when work moves from one
+// server (Prev) to another.
+type FailoverEvent struct {
+	PrevHost, PrevPort string
+	Host, Port         string
+
+	// Role of the server now being used.
+	Role ServerRole
+}
+
+func (c *libpqConn) Host() string {
+	return C.GoString(C.PQhost(c.db))
+}
+
+func (c *libpqConn) Port() string {
+	return C.GoString(C.PQport(c.db))
+}
+
+func (c *libpqConn) ServerRole() (ServerRole, error) {
+	// Postgres 14 and later report this without a round trip
+	if c.ServerVersion() >= 140000 {
+		if val, ok := c.ParameterStatus("in_hot_standby"); ok {
+			if val == "on" {
+				return RoleStandby, nil
+			}
+			return RolePrimary, nil
+		}
+	}
+
+	if err := c.ensureConn(); err != nil {
+		return RoleUnknown, err
+	}
+	cres, err := c.query("SELECT pg_is_in_recovery()")
+	if err != nil {
+		return RoleUnknown, err
+	}
+	defer C.PQclear(cres)
+	if C.GoString(C.PQgetvalue(cres, 0, 0)) == "t" {
+		return RoleStandby, nil
+	}
+	return RolePrimary, nil
+}
+
+// noteConnected records the server conn reached, reporting a FailoverEvent
+// if it differs from the one the previous connection reached.
+func (c *Connector) noteConnected(conn *libpqConn) {
+	host, port := conn.Host(), conn.Port()
+
+	c.mu.Lock()
+	prevHost, prevPort := c.host, c.port
+	c.host, c.port = host, port
+	c.mu.Unlock()
+
+	if prevHost == "" || (prevHost == host && prevPort == port) || c.OnFailover == nil {
+		return
+	}
+	role, _ := conn.ServerRole()
+	c.OnFailover(FailoverEvent{
+		PrevHost: prevHost,
+		PrevPort: prevPort,
+		Host:     host,
+		Port:     port,
+		Role:     role,
+	})
+}
@@ -0,0 +1,108 @@
+package libpq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func TestConnInfo(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %s", err)
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(dc interface{}) error {
+		pc := dc.(libpq.Conn)
+		if pc.Port() == "" {
+			t.Errorf("Expected a port")
+		}
+		role, err := pc.ServerRole()
+		if err != nil {
+			return err
+		}
+		if role != libpq.RolePrimary {
+			t.Errorf("Expected test server to be a primary, got %s", role)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to get connection info: %s", err)
+	}
+}
+
+func TestReadOnlyTx(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to begin read-only transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("create temp table test (i int)"); err == nil {
+		t.Fatalf("Expected CREATE TABLE to fail in a read-only transaction")
+	}
+}
+
+func TestRoutingFallback(t *testing.T) {
+	// the "standby" is really the primary, so read-only transactions should
+	// fall back to the primary and report it
+	var events []libpq.FailoverEvent
+	rc := libpq.NewRoutingConnector(libpq.NewConnector(testDSN()), libpq.NewConnector(testDSN()))
+	rc.OnFailover = func(ev libpq.FailoverEvent) { events = append(events, ev) }
+	db := sql.OpenDB(rc)
+	defer db.Close()
+
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to begin read-only transaction: %s", err)
+	}
+	var one int64
+	if err = tx.QueryRow("select 1").Scan(&one); err != nil || one != 1 {
+		t.Fatalf("Query in routed transaction failed: %v", err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %s", err)
+	}
+
+	if len(events) != 1 || events[0].Role != libpq.RolePrimary {
+		t.Fatalf("Expected one failover to the primary, got %v", events)
+	}
+}
+
+func TestRoutedStmt(t *testing.T) {
+	rc := libpq.NewRoutingConnector(libpq.NewConnector(testDSN()), libpq.NewConnector(testDSN()))
+	db := sql.OpenDB(rc)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	stmt, err := db.Prepare("select current_setting('transaction_read_only')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	// a statement used in a transaction runs on the transaction's connection
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to begin read-only transaction: %s", err)
+	}
+	defer tx.Rollback()
+	var readOnly string
+	if err := tx.Stmt(stmt).QueryRow().Scan(&readOnly); err != nil || readOnly != "on" {
+		t.Errorf("Statement not run in the read-only transaction: %v (got %q)", err, readOnly)
+	}
+	tx.Rollback()
+
+	if err := stmt.QueryRow().Scan(&readOnly); err != nil || readOnly != "off" {
+		t.Errorf("Statement not run outside the transaction: %v (got %q)", err, readOnly)
+	}
+}
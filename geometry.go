@@ -0,0 +1,470 @@
+package libpq
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Point is the value of a point column, (x,y).
+type Point struct {
+	X, Y float64
+}
+
+// Line is the value of a line column, the infinite line Ax + By + C = 0.
+type Line struct {
+	A, B, C float64
+}
+
+// LineSegment is the value of an lseg column, [(x1,y1),(x2,y2)].
+type LineSegment [2]Point
+
+// Box is the value of a box column, (x1,y1),(x2,y2). Postgres stores the
+// upper right corner first, whichever order the corners are given in.
+type Box [2]Point
+
+// Path is the value of a path column: [(x1,y1),...] if open,
+// ((x1,y1),...) if closed.
+type Path struct {
+	Points []Point
+	Closed bool
+}
+
+// Polygon is the value of a polygon column, ((x1,y1),...).
+type Polygon []Point
+
+// Circle is the value of a circle column, <(x,y),r>.
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+var errGeometryFormat = errors.New("libpq: invalid geometric format")
+
+// parseFloats returns the numbers in the text representation of a geometric
+// value, after checking that it starts with open; the brackets around and
+// between them are ignored.
+func parseFloats(s string, open byte) ([]float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s[0] != open {
+		return nil, errGeometryFormat
+	}
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return strings.ContainsRune("()[]{}<>, ", r)
+	})
+	fs := make([]float64, len(fields))
+	for i, f := range fields {
+		var err error
+		if fs[i], err = strconv.ParseFloat(f, 64); err != nil {
+			return nil, errGeometryFormat
+		}
+	}
+	return fs, nil
+}
+
+// parsePoints parses the numbers in s as a list of n points, or any number
+// if n is 0.
+func parsePoints(s string, open byte, n int) ([]Point, error) {
+	fs, err := parseFloats(s, open)
+	if err != nil {
+		return nil, err
+	}
+	if len(fs)%2 != 0 || (n > 0 && len(fs) != 2*n) {
+		return nil, errGeometryFormat
+	}
+	ps := make([]Point, len(fs)/2)
+	for i := range ps {
+		ps[i] = Point{fs[2*i], fs[2*i+1]}
+	}
+	return ps, nil
+}
+
+func formatPoints(open, close string, ps []Point) string {
+	var b strings.Builder
+	b.WriteString(open)
+	for i, p := range ps {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(p.String())
+	}
+	b.WriteString(close)
+	return b.String()
+}
+
+func (p Point) String() string {
+	return "(" + formatFloat(p.X) + "," + formatFloat(p.Y) + ")"
+}
+
+// Implement Valuer interface.
+func (p Point) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+// Implement Scanner interface.
+func (p *Point) Scan(src interface{}) error {
+	s, null, err := srcText(src, "Point")
+	if err != nil || null {
+		*p = Point{}
+		return err
+	}
+	ps, err := parsePoints(s, '(', 1)
+	if err != nil {
+		return err
+	}
+	*p = ps[0]
+	return nil
+}
+
+func (l Line) String() string {
+	return "{" + formatFloat(l.A) + "," + formatFloat(l.B) + "," + formatFloat(l.C) + "}"
+}
+
+// Implement Valuer interface.
+func (l Line) Value() (driver.Value, error) {
+	return l.String(), nil
+}
+
+// Implement Scanner interface.
+func (l *Line) Scan(src interface{}) error {
+	s, null, err := srcText(src, "Line")
+	if err != nil || null {
+		*l = Line{}
+		return err
+	}
+	fs, err := parseFloats(s, '{')
+	if err != nil {
+		return err
+	}
+	if len(fs) != 3 {
+		return errGeometryFormat
+	}
+	*l = Line{fs[0], fs[1], fs[2]}
+	return nil
+}
+
+func (l LineSegment) String() string {
+	return formatPoints("[", "]", l[:])
+}
+
+// Implement Valuer interface.
+func (l LineSegment) Value() (driver.Value, error) {
+	return l.String(), nil
+}
+
+// Implement Scanner interface.
+func (l *LineSegment) Scan(src interface{}) error {
+	s, null, err := srcText(src, "LineSegment")
+	if err != nil || null {
+		*l = LineSegment{}
+		return err
+	}
+	ps, err := parsePoints(s, '[', 2)
+	if err != nil {
+		return err
+	}
+	copy(l[:], ps)
+	return nil
+}
+
+func (b Box) String() string {
+	return formatPoints("", "", b[:])
+}
+
+// Implement Valuer interface.
+func (b Box) Value() (driver.Value, error) {
+	return b.String(), nil
+}
+
+// Implement Scanner interface.
+func (b *Box) Scan(src interface{}) error {
+	s, null, err := srcText(src, "Box")
+	if err != nil || null {
+		*b = Box{}
+		return err
+	}
+	ps, err := parsePoints(s, '(', 2)
+	if err != nil {
+		return err
+	}
+	copy(b[:], ps)
+	return nil
+}
+
+func (p Path) String() string {
+	if p.Closed {
+		return formatPoints("(", ")", p.Points)
+	}
+	return formatPoints("[", "]", p.Points)
+}
+
+// Implement Valuer interface.
+func (p Path) Value() (driver.Value, error) {
+	if p.Points == nil {
+		return nil, nil
+	}
+	return p.String(), nil
+}
+
+// Implement Scanner interface.
+func (p *Path) Scan(src interface{}) error {
+	s, null, err := srcText(src, "Path")
+	if err != nil || null {
+		*p = Path{}
+		return err
+	}
+	s = strings.TrimSpace(s)
+	open := byte('[')
+	if strings.HasPrefix(s, "(") {
+		open = '('
+	}
+	ps, err := parsePoints(s, open, 0)
+	if err != nil {
+		return err
+	}
+	*p = Path{Points: ps, Closed: open == '('}
+	return nil
+}
+
+func (p Polygon) String() string {
+	return formatPoints("(", ")", p)
+}
+
+// Implement Valuer interface.
+func (p Polygon) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return p.String(), nil
+}
+
+// Implement Scanner interface.
+func (p *Polygon) Scan(src interface{}) error {
+	s, null, err := srcText(src, "Polygon")
+	if err != nil || null {
+		*p = nil
+		return err
+	}
+	ps, err := parsePoints(s, '(', 0)
+	if err != nil {
+		return err
+	}
+	*p = ps
+	return nil
+}
+
+func (c Circle) String() string {
+	return "<" + c.Center.String() + "," + formatFloat(c.Radius) + ">"
+}
+
+// Implement Valuer interface.
+func (c Circle) Value() (driver.Value, error) {
+	return c.String(), nil
+}
+
+// Implement Scanner interface.
+func (c *Circle) Scan(src interface{}) error {
+	s, null, err := srcText(src, "Circle")
+	if err != nil || null {
+		*c = Circle{}
+		return err
+	}
+	fs, err := parseFloats(s, '<')
+	if err != nil {
+		return err
+	}
+	if len(fs) != 3 {
+		return errGeometryFormat
+	}
+	*c = Circle{Point{fs[0], fs[1]}, fs[2]}
+	return nil
+}
+
+// The binary formats are sequences of float8s, with a closed flag and point
+// count before a path's points and a point count before a polygon's.
+
+func appendFloats(b []byte, fs ...float64) []byte {
+	for _, f := range fs {
+		b = binary.BigEndian.AppendUint64(b, math.Float64bits(f))
+	}
+	return b
+}
+
+func appendPoints(b []byte, ps []Point) []byte {
+	for _, p := range ps {
+		b = appendFloats(b, p.X, p.Y)
+	}
+	return b
+}
+
+// readFloats decodes len(fs) float8s from the start of src, returning the
+// rest of it.
+func readFloats(src []byte, fs ...*float64) ([]byte, error) {
+	if len(src) < 8*len(fs) {
+		return nil, errGeometryFormat
+	}
+	for _, f := range fs {
+		*f = math.Float64frombits(binary.BigEndian.Uint64(src))
+		src = src[8:]
+	}
+	return src, nil
+}
+
+// readPoints decodes a point count followed by that many points.
+func readPoints(src []byte) ([]Point, error) {
+	if len(src) < 4 {
+		return nil, errGeometryFormat
+	}
+	n := int(binary.BigEndian.Uint32(src))
+	src = src[4:]
+	if len(src) != 16*n {
+		return nil, errGeometryFormat
+	}
+	ps := make([]Point, n)
+	for i := range ps {
+		src, _ = readFloats(src, &ps[i].X, &ps[i].Y)
+	}
+	return ps, nil
+}
+
+// MarshalBinary returns p in Postgres's binary format.
+func (p Point) MarshalBinary() ([]byte, error) {
+	return appendFloats(nil, p.X, p.Y), nil
+}
+
+// UnmarshalBinary sets p from Postgres's binary format.
+func (p *Point) UnmarshalBinary(src []byte) error {
+	rest, err := readFloats(src, &p.X, &p.Y)
+	if err == nil && len(rest) != 0 {
+		err = errGeometryFormat
+	}
+	return err
+}
+
+// MarshalBinary returns l in Postgres's binary format.
+func (l Line) MarshalBinary() ([]byte, error) {
+	return appendFloats(nil, l.A, l.B, l.C), nil
+}
+
+// UnmarshalBinary sets l from Postgres's binary format.
+func (l *Line) UnmarshalBinary(src []byte) error {
+	rest, err := readFloats(src, &l.A, &l.B, &l.C)
+	if err == nil && len(rest) != 0 {
+		err = errGeometryFormat
+	}
+	return err
+}
+
+// MarshalBinary returns l in Postgres's binary format.
+func (l LineSegment) MarshalBinary() ([]byte, error) {
+	return appendPoints(nil, l[:]), nil
+}
+
+// UnmarshalBinary sets l from Postgres's binary format.
+func (l *LineSegment) UnmarshalBinary(src []byte) error {
+	rest, err := readFloats(src, &l[0].X, &l[0].Y, &l[1].X, &l[1].Y)
+	if err == nil && len(rest) != 0 {
+		err = errGeometryFormat
+	}
+	return err
+}
+
+// MarshalBinary returns b in Postgres's binary format.
+func (b Box) MarshalBinary() ([]byte, error) {
+	return appendPoints(nil, b[:]), nil
+}
+
+// UnmarshalBinary sets b from Postgres's binary format.
+func (b *Box) UnmarshalBinary(src []byte) error {
+	rest, err := readFloats(src, &b[0].X, &b[0].Y, &b[1].X, &b[1].Y)
+	if err == nil && len(rest) != 0 {
+		err = errGeometryFormat
+	}
+	return err
+}
+
+// MarshalBinary returns p in Postgres's binary format.
+func (p Path) MarshalBinary() ([]byte, error) {
+	b := []byte{0}
+	if p.Closed {
+		b[0] = 1
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(p.Points)))
+	return appendPoints(b, p.Points), nil
+}
+
+// UnmarshalBinary sets p from Postgres's binary format.
+func (p *Path) UnmarshalBinary(src []byte) error {
+	if len(src) < 1 {
+		return errGeometryFormat
+	}
+	ps, err := readPoints(src[1:])
+	if err != nil {
+		return err
+	}
+	*p = Path{Points: ps, Closed: src[0] != 0}
+	return nil
+}
+
+// MarshalBinary returns p in Postgres's binary format.
+func (p Polygon) MarshalBinary() ([]byte, error) {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(p)))
+	return appendPoints(b, p), nil
+}
+
+// UnmarshalBinary sets p from Postgres's binary format.
+func (p *Polygon) UnmarshalBinary(src []byte) error {
+	ps, err := readPoints(src)
+	if err != nil {
+		return err
+	}
+	*p = ps
+	return nil
+}
+
+// MarshalBinary returns c in Postgres's binary format.
+func (c Circle) MarshalBinary() ([]byte, error) {
+	return appendFloats(nil, c.Center.X, c.Center.Y, c.Radius), nil
+}
+
+// UnmarshalBinary sets c from Postgres's binary format.
+func (c *Circle) UnmarshalBinary(src []byte) error {
+	rest, err := readFloats(src, &c.Center.X, &c.Center.Y, &c.Radius)
+	if err == nil && len(rest) != 0 {
+		err = errGeometryFormat
+	}
+	return err
+}
+
+// decodeGeometry converts a geometric value in binary format to its text
+// representation.
+func decodeGeometry(typ oid, src []byte) (string, error) {
+	var v interface {
+		UnmarshalBinary([]byte) error
+		fmt.Stringer
+	}
+	switch typ {
+	case oidPoint:
+		v = &Point{}
+	case oidLine:
+		v = &Line{}
+	case oidLseg:
+		v = &LineSegment{}
+	case oidBox:
+		v = &Box{}
+	case oidPath:
+		v = &Path{}
+	case oidPolygon:
+		v = &Polygon{}
+	case oidCircle:
+		v = &Circle{}
+	}
+	if err := v.UnmarshalBinary(src); err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
@@ -0,0 +1,86 @@
+package libpq_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+type geometry interface {
+	driver.Valuer
+	sql.Scanner
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+var geometryTests = []struct {
+	typ   string
+	text  string
+	value geometry
+}{
+	{"point", "(1.5,-2)", &libpq.Point{X: 1.5, Y: -2}},
+	{"line", "{1,-1,0}", &libpq.Line{A: 1, B: -1, C: 0}},
+	{"lseg", "[(0,0),(1,1)]", &libpq.LineSegment{{X: 0, Y: 0}, {X: 1, Y: 1}}},
+	{"box", "(2,2),(0,0)", &libpq.Box{{X: 2, Y: 2}, {X: 0, Y: 0}}},
+	{"path", "[(0,0),(1,1),(2,0)]", &libpq.Path{Points: []libpq.Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 2, Y: 0}}}},
+	{"path", "((0,0),(1,1),(2,0))", &libpq.Path{Points: []libpq.Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 2, Y: 0}}, Closed: true}},
+	{"polygon", "((0,0),(0,1),(1,0))", &libpq.Polygon{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 0}}},
+	{"circle", "<(1,2),3>", &libpq.Circle{Center: libpq.Point{X: 1, Y: 2}, Radius: 3}},
+}
+
+func TestGeometryScan(t *testing.T) {
+	for _, test := range geometryTests {
+		v := reflect.New(reflect.TypeOf(test.value).Elem()).Interface().(geometry)
+		if err := v.Scan(test.text); err != nil {
+			t.Errorf("Failed to Scan() %s %q: %s", test.typ, test.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, test.value) {
+			t.Errorf("Scanned %s %q as %+v, expected %+v", test.typ, test.text, v, test.value)
+		}
+		if text, err := v.Value(); err != nil || text != test.text {
+			t.Errorf("Value() of %+v = %q, expected %q (%v)", v, text, test.text, err)
+		}
+
+		b, err := v.MarshalBinary()
+		if err != nil {
+			t.Errorf("MarshalBinary() of %+v failed: %s", v, err)
+			continue
+		}
+		back := reflect.New(reflect.TypeOf(test.value).Elem()).Interface().(geometry)
+		if err := back.UnmarshalBinary(b); err != nil || !reflect.DeepEqual(back, v) {
+			t.Errorf("%+v did not round-trip through binary: %v", v, err)
+		}
+	}
+
+	var p libpq.Point
+	if err := p.Scan("(Infinity,-Infinity)"); err != nil || !math.IsInf(p.X, 1) || !math.IsInf(p.Y, -1) {
+		t.Errorf("Unexpected infinite point %+v: %v", p, err)
+	}
+	for _, text := range []string{"", "1,2", "(1,2,3)", "(a,b)", "[(1,2)]"} {
+		if err := p.Scan(text); err == nil {
+			t.Errorf("Expected error scanning point %q", text)
+		}
+	}
+}
+
+func TestGeometry(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	for _, test := range geometryTests {
+		v := reflect.New(reflect.TypeOf(test.value).Elem()).Interface().(geometry)
+		if err := db.QueryRow("select $1::"+test.typ, test.value).Scan(v); err != nil {
+			t.Errorf("Failed to Scan() %s: %s", test.typ, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, test.value) {
+			t.Errorf("%s %+v came back as %+v", test.typ, test.value, v)
+		}
+	}
+}
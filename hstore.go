@@ -0,0 +1,162 @@
+package libpq
+
+import (
+	"database/sql/driver"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Hstore is the value of an hstore column, with nil standing for a NULL
+// value. Hstores (and map[string]string values) passed as parameters are sent
+// as hstore, whose OID is looked up on each server since it comes from an
+// extension.
+type Hstore map[string]*string
+
+var errHstoreFormat = errors.New("libpq: invalid hstore format")
+
+// Implement Valuer interface.
+func (h Hstore) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	return formatHstore(h), nil
+}
+
+// Implement Scanner interface.
+func (h *Hstore) Scan(src interface{}) error {
+	s, null, err := srcText(src, "Hstore")
+	if err != nil || null {
+		*h = nil
+		return err
+	}
+	m, err := parseHstore(s)
+	if err != nil {
+		return err
+	}
+	*h = m
+	return nil
+}
+
+func parseHstore(s string) (Hstore, error) {
+	h := make(Hstore)
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return h, nil
+		}
+
+		key, quoted, rest, err := scanHstoreString(s)
+		if err != nil {
+			return nil, err
+		}
+		rest = strings.TrimLeft(rest, " ")
+		if !strings.HasPrefix(rest, "=>") {
+			return nil, errHstoreFormat
+		}
+		val, quoted, rest, err := scanHstoreString(strings.TrimLeft(rest[2:], " "))
+		if err != nil {
+			return nil, err
+		}
+		if !quoted && strings.EqualFold(val, "NULL") {
+			h[key] = nil
+		} else {
+			h[key] = &val
+		}
+
+		s = strings.TrimLeft(rest, " ")
+		if s != "" {
+			if s[0] != ',' {
+				return nil, errHstoreFormat
+			}
+			s = s[1:]
+		}
+	}
+}
+
+// scanHstoreString reads a key or value, quoted or not, from the start of s.
+func scanHstoreString(s string) (str string, quoted bool, rest string, err error) {
+	if s == "" {
+		return "", false, s, errHstoreFormat
+	}
+	if s[0] != '"' {
+		end := strings.IndexAny(s, "=, ")
+		if end < 0 {
+			end = len(s)
+		}
+		if end == 0 {
+			return "", false, s, errHstoreFormat
+		}
+		return s[:end], false, s[end:], nil
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i >= len(s) {
+				return "", false, s, errHstoreFormat
+			}
+		case '"':
+			return b.String(), true, s[i+1:], nil
+		}
+		b.WriteByte(s[i])
+	}
+	return "", false, s, errHstoreFormat
+}
+
+// formatHstore builds the text representation of h, with keys sorted so that
+// equal maps produce the same text.
+func formatHstore(h map[string]*string) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writeHstoreString(&b, k)
+		b.WriteString("=>")
+		if v := h[k]; v == nil {
+			b.WriteString("NULL")
+		} else {
+			writeHstoreString(&b, *v)
+		}
+	}
+	return b.String()
+}
+
+func writeHstoreString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+}
+
+// encodeHstore returns the text representation of the hstore parameter v, if
+// it is one.
+func encodeHstore(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case Hstore:
+		return formatHstore(v), true
+	case map[string]*string:
+		return formatHstore(v), true
+	case map[string]string:
+		h := make(map[string]*string, len(v))
+		for k, val := range v {
+			val := val
+			h[k] = &val
+		}
+		return formatHstore(h), true
+	}
+	return "", false
+}
@@ -0,0 +1,64 @@
+package libpq_test
+
+import (
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func TestHstoreScan(t *testing.T) {
+	var h libpq.Hstore
+	if err := h.Scan(`"a"=>"1", "b\"c"=>NULL, "d\\"=>"NULL", e => f`); err != nil {
+		t.Fatalf("Failed to Scan() hstore: %s", err)
+	}
+	if len(h) != 4 || *h["a"] != "1" || h[`b"c`] != nil || *h[`d\`] != "NULL" || *h["e"] != "f" {
+		t.Errorf("Unexpected hstore %v", h)
+	}
+
+	v, err := h.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != `"a"=>"1", "b\"c"=>NULL, "d\\"=>"NULL", "e"=>"f"` {
+		t.Errorf("Unexpected hstore text %s", v)
+	}
+
+	if err := h.Scan(""); err != nil || h == nil || len(h) != 0 {
+		t.Errorf("Unexpected empty hstore %v: %v", h, err)
+	}
+	for _, text := range []string{`"a"`, `"a"=>`, `"a"=>"b`, `"a"=>"b" "c"=>"d"`} {
+		if err := h.Scan(text); err == nil {
+			t.Errorf("Expected error scanning %q", text)
+		}
+	}
+}
+
+func TestHstore(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+	if _, err := db.Exec("CREATE EXTENSION IF NOT EXISTS hstore"); err != nil {
+		t.Skipf("hstore extension not available: %s", err)
+	}
+
+	one := "1"
+	in := libpq.Hstore{"a": &one, `quote"back\slash`: nil}
+	var out libpq.Hstore
+	if err := db.QueryRow("select $1", in).Scan(&out); err != nil {
+		t.Fatalf("Failed to Scan() hstore: %s", err)
+	}
+	if len(out) != 2 || *out["a"] != "1" || out[`quote"back\slash`] != nil {
+		t.Errorf("Unexpected hstore %v", out)
+	}
+
+	// map[string]string parameters are sent as hstore too
+	var val string
+	if err := db.QueryRow("select $1 -> 'k'", map[string]string{"k": "v"}).Scan(&val); err != nil || val != "v" {
+		t.Errorf("map[string]string parameter not sent as hstore: %v (got %q)", err, val)
+	}
+
+	// while a nil Hstore is NULL
+	var isNull bool
+	if err := db.QueryRow("select $1::hstore is null", libpq.Hstore(nil)).Scan(&isNull); err != nil || !isNull {
+		t.Errorf("nil Hstore not sent as NULL: %v", err)
+	}
+}
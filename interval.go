@@ -0,0 +1,344 @@
+package libpq
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interval is the value of an interval column. Postgres keeps months, days
+// and smaller units separately, since the length of a month or day varies;
+// so does Interval.
+//
+// time.Duration values passed as parameters are sent as intervals.
+type Interval struct {
+	Months       int32
+	Days         int32
+	Microseconds int64
+}
+
+const (
+	microsPerSecond = 1000000
+	microsPerMinute = 60 * microsPerSecond
+	microsPerHour   = 60 * microsPerMinute
+)
+
+// Duration converts i to a time.Duration, counting a month as 30 days and a
+// day as 24 hours the way Postgres does when it has to (e.g., for
+// justify_interval or EXTRACT(EPOCH ...)). This is lossy, and intervals
+// longer than about 292 years overflow.
+func (i Interval) Duration() time.Duration {
+	days := int64(i.Months)*30 + int64(i.Days)
+	return time.Duration(days)*24*time.Hour + time.Duration(i.Microseconds)*time.Microsecond
+}
+
+// String returns i in ISO 8601 format (e.g. "P1Y2M3DT4H5M6.5S"), which
+// Postgres accepts as input whatever its IntervalStyle.
+func (i Interval) String() string {
+	if i == (Interval{}) {
+		return "PT0S"
+	}
+
+	var b strings.Builder
+	b.WriteByte('P')
+	if years := i.Months / 12; years != 0 {
+		fmt.Fprintf(&b, "%dY", years)
+	}
+	if months := i.Months % 12; months != 0 {
+		fmt.Fprintf(&b, "%dM", months)
+	}
+	if i.Days != 0 {
+		fmt.Fprintf(&b, "%dD", i.Days)
+	}
+	if i.Microseconds != 0 {
+		b.WriteByte('T')
+		us := i.Microseconds
+		if hours := us / microsPerHour; hours != 0 {
+			fmt.Fprintf(&b, "%dH", hours)
+		}
+		if minutes := us % microsPerHour / microsPerMinute; minutes != 0 {
+			fmt.Fprintf(&b, "%dM", minutes)
+		}
+		if us %= microsPerMinute; us != 0 {
+			if us < 0 {
+				b.WriteByte('-')
+				us = -us
+			}
+			b.WriteString(strconv.FormatInt(us/microsPerSecond, 10))
+			if frac := us % microsPerSecond; frac != 0 {
+				b.WriteString(strings.TrimRight(fmt.Sprintf(".%06d", frac), "0"))
+			}
+			b.WriteByte('S')
+		}
+	}
+	return b.String()
+}
+
+// Implement Valuer interface.
+func (i Interval) Value() (driver.Value, error) {
+	return i.String(), nil
+}
+
+// Implement Scanner interface.
+func (i *Interval) Scan(src interface{}) error {
+	var s string
+	switch src := src.(type) {
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	default:
+		return fmt.Errorf("libpq: cannot scan %T into Interval", src)
+	}
+	v, err := ParseInterval(s)
+	if err != nil {
+		return err
+	}
+	*i = v
+	return nil
+}
+
+// ParseInterval parses an interval in any of the output formats selected by
+// the IntervalStyle setting:
+//
+//	postgres          1 year 2 mons -3 days +04:05:06.7
+//	postgres_verbose  @ 1 year 2 mons -3 days 4 hours 5 mins 6.7 secs ago
+//	sql_standard      +1-2 -3 +4:05:06.7
+//	iso_8601          P1Y2M-3DT4H5M6.7S
+func ParseInterval(s string) (Interval, error) {
+	var i Interval
+	var err error
+	switch {
+	case strings.TrimSpace(s) == "":
+		err = fmt.Errorf("empty interval")
+	case strings.HasPrefix(s, "P"):
+		err = i.parseISO(s[1:])
+	case strings.HasPrefix(s, "@"):
+		err = i.parseFields(strings.Fields(s[1:]), true)
+	default:
+		err = i.parseFields(strings.Fields(s), false)
+	}
+	if err != nil {
+		return Interval{}, fmt.Errorf("libpq: invalid interval %q: %s", s, err)
+	}
+	return i, nil
+}
+
+// parseFields handles the postgres, postgres_verbose and sql_standard
+// formats, all of which are space-separated fields.
+func (i *Interval) parseFields(fields []string, verbose bool) error {
+	negate := false
+	if verbose && len(fields) > 0 && fields[len(fields)-1] == "ago" {
+		negate = true
+		fields = fields[:len(fields)-1]
+	}
+
+	// In sql_standard format, a leading '-' applies to every field unless
+	// the later fields have signs of their own.
+	if !verbose && len(fields) > 1 && strings.HasPrefix(fields[0], "-") && !hasUnit(fields) {
+		explicit := false
+		for _, f := range fields[1:] {
+			explicit = explicit || f[0] == '+' || f[0] == '-'
+		}
+		if !explicit {
+			negate = true
+			fields = append([]string{fields[0][1:]}, fields[1:]...)
+		}
+	}
+
+	for n := 0; n < len(fields); n++ {
+		f := fields[n]
+		switch {
+		case n+1 < len(fields) && isUnit(fields[n+1]):
+			if err := i.addUnit(f, fields[n+1]); err != nil {
+				return err
+			}
+			n++
+		case strings.Contains(f, ":"):
+			us, err := parseTime(f)
+			if err != nil {
+				return err
+			}
+			i.Microseconds += us
+		case strings.Contains(strings.TrimLeft(f, "+-"), "-"):
+			// sql_standard year-month; the sign applies to both parts
+			sign, ym := splitSign(f)
+			dash := strings.IndexByte(ym, '-')
+			years, err := strconv.ParseInt(ym[:dash], 10, 32)
+			if err != nil {
+				return err
+			}
+			months, err := strconv.ParseInt(ym[dash+1:], 10, 32)
+			if err != nil {
+				return err
+			}
+			i.Months += int32(sign * (years*12 + months))
+		default:
+			// sql_standard days
+			days, err := strconv.ParseInt(f, 10, 32)
+			if err != nil {
+				return err
+			}
+			i.Days += int32(days)
+		}
+	}
+
+	if negate {
+		i.Months, i.Days, i.Microseconds = -i.Months, -i.Days, -i.Microseconds
+	}
+	return nil
+}
+
+func hasUnit(fields []string) bool {
+	for _, f := range fields {
+		if isUnit(f) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnit(f string) bool {
+	return f != "" && (f[0] >= 'a' && f[0] <= 'z')
+}
+
+// addUnit adds num of the given unit (e.g. "mons", "secs") to i.
+func (i *Interval) addUnit(num, unit string) error {
+	unit = strings.TrimSuffix(unit, "s")
+	switch unit {
+	case "year", "mon", "day", "week":
+		n, err := strconv.ParseInt(num, 10, 32)
+		if err != nil {
+			return err
+		}
+		switch unit {
+		case "year":
+			i.Months += int32(n * 12)
+		case "mon":
+			i.Months += int32(n)
+		case "week":
+			i.Days += int32(n * 7)
+		default:
+			i.Days += int32(n)
+		}
+	case "hour", "min", "sec":
+		us, err := parseSeconds(num)
+		if err != nil {
+			return err
+		}
+		switch unit {
+		case "hour":
+			i.Microseconds += us * 3600
+		case "min":
+			i.Microseconds += us * 60
+		default:
+			i.Microseconds += us
+		}
+	default:
+		return fmt.Errorf("unknown unit %q", unit)
+	}
+	return nil
+}
+
+// parseISO handles the iso_8601 format, after the leading "P".
+func (i *Interval) parseISO(s string) error {
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' {
+			inTime = true
+			s = s[1:]
+			continue
+		}
+		end := strings.IndexAny(s, "YMWDHS")
+		if end <= 0 {
+			return fmt.Errorf("missing unit")
+		}
+		num, unit := s[:end], s[end]
+		s = s[end+1:]
+
+		var err error
+		switch {
+		case !inTime && unit == 'Y':
+			err = i.addUnit(num, "year")
+		case !inTime && unit == 'M':
+			err = i.addUnit(num, "mon")
+		case !inTime && unit == 'W':
+			err = i.addUnit(num, "week")
+		case !inTime && unit == 'D':
+			err = i.addUnit(num, "day")
+		case inTime && unit == 'H':
+			err = i.addUnit(num, "hour")
+		case inTime && unit == 'M':
+			err = i.addUnit(num, "min")
+		case inTime && unit == 'S':
+			err = i.addUnit(num, "sec")
+		default:
+			err = fmt.Errorf("unexpected unit %c", unit)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitSign(s string) (int64, string) {
+	switch {
+	case strings.HasPrefix(s, "-"):
+		return -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		return 1, s[1:]
+	}
+	return 1, s
+}
+
+// parseTime converts [+-]h:mm:ss[.ffffff] to microseconds.
+func parseTime(s string) (int64, error) {
+	sign, s := splitSign(s)
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	hours, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	var us int64
+	if len(parts) == 3 {
+		if us, err = parseSeconds(parts[2]); err != nil {
+			return 0, err
+		}
+	}
+	return sign * (hours*microsPerHour + minutes*microsPerMinute + us), nil
+}
+
+// parseSeconds converts [+-]s[.ffffff] to microseconds without going
+// through a float.
+func parseSeconds(s string) (int64, error) {
+	sign, s := splitSign(s)
+	whole, frac := s, ""
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		whole, frac = s[:dot], s[dot+1:]
+	}
+	secs, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	var us int64
+	if frac != "" {
+		if len(frac) > 6 {
+			frac = frac[:6]
+		}
+		frac += strings.Repeat("0", 6-len(frac))
+		if us, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	return sign * (secs*microsPerSecond + us), nil
+}
@@ -0,0 +1,95 @@
+package libpq_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+var intervalTests = []struct {
+	text   string
+	expect libpq.Interval
+}{
+	// postgres
+	{"00:00:00", libpq.Interval{}},
+	{"1 year 2 mons", libpq.Interval{Months: 14}},
+	{"3 days 04:05:06", libpq.Interval{Days: 3, Microseconds: 14706000000}},
+	{"-1 years -2 mons +3 days -04:05:06.5", libpq.Interval{Months: -14, Days: 3, Microseconds: -14706500000}},
+	{"1 day", libpq.Interval{Days: 1}},
+	{"00:00:00.000001", libpq.Interval{Microseconds: 1}},
+	// postgres_verbose
+	{"@ 0", libpq.Interval{}},
+	{"@ 1 year 2 mons", libpq.Interval{Months: 14}},
+	{"@ 3 days 4 hours 5 mins 6.5 secs", libpq.Interval{Days: 3, Microseconds: 14706500000}},
+	{"@ 1 year 2 mons -3 days 4 hours 5 mins 6 secs ago", libpq.Interval{Months: -14, Days: 3, Microseconds: -14706000000}},
+	// sql_standard
+	{"0", libpq.Interval{}},
+	{"1-2", libpq.Interval{Months: 14}},
+	{"-1-2", libpq.Interval{Months: -14}},
+	{"3 4:05:06", libpq.Interval{Days: 3, Microseconds: 14706000000}},
+	{"-3 4:05:06", libpq.Interval{Days: -3, Microseconds: -14706000000}},
+	{"-1-2 +3 -4:05:06", libpq.Interval{Months: -14, Days: 3, Microseconds: -14706000000}},
+	// iso_8601
+	{"PT0S", libpq.Interval{}},
+	{"P1Y2M", libpq.Interval{Months: 14}},
+	{"P3DT4H5M6.5S", libpq.Interval{Days: 3, Microseconds: 14706500000}},
+	{"P-1Y-2M3DT-4H-5M-6S", libpq.Interval{Months: -14, Days: 3, Microseconds: -14706000000}},
+}
+
+func TestParseInterval(t *testing.T) {
+	for _, test := range intervalTests {
+		i, err := libpq.ParseInterval(test.text)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", test.text, err)
+			continue
+		}
+		if i != test.expect {
+			t.Errorf("Parsed %q as %+v, expected %+v", test.text, i, test.expect)
+		}
+
+		// String() must produce something we (and Postgres) can parse back
+		if back, err := libpq.ParseInterval(i.String()); err != nil || back != i {
+			t.Errorf("%+v did not round-trip through %q: %v", i, i.String(), err)
+		}
+	}
+
+	for _, text := range []string{"", "P1X", "1 fortnight", "1:2:3:4"} {
+		if _, err := libpq.ParseInterval(text); err == nil {
+			t.Errorf("Expected error parsing %q", text)
+		}
+	}
+}
+
+func TestIntervalDuration(t *testing.T) {
+	i := libpq.Interval{Months: 1, Days: 2, Microseconds: 3}
+	expect := 32*24*time.Hour + 3*time.Microsecond
+	if d := i.Duration(); d != expect {
+		t.Fatalf("Duration() = %s, expected %s", d, expect)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	for _, style := range []string{"postgres", "postgres_verbose", "sql_standard", "iso_8601"} {
+		mustExec(t, db, "SET IntervalStyle = "+style)
+		for _, test := range intervalTests {
+			var i libpq.Interval
+			if err := db.QueryRow("select $1::interval", test.expect).Scan(&i); err != nil {
+				t.Fatalf("Failed to Scan() interval with style %s: %s", style, err)
+			}
+			if i != test.expect {
+				t.Errorf("Style %s: got %+v, expected %+v", style, i, test.expect)
+			}
+		}
+	}
+
+	var ok bool
+	err := db.QueryRow("select $1 = interval '1 hour 30 minutes'", 90*time.Minute).Scan(&ok)
+	if err != nil || !ok {
+		t.Fatalf("time.Duration parameter not sent as an interval: %v", err)
+	}
+}
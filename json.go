@@ -0,0 +1,51 @@
+package libpq
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// JSON wraps a Go value stored in a json or jsonb column, converting it with
+// encoding/json. To scan into an existing value, point V at it:
+//
+//	var attrs map[string]int
+//	err := row.Scan(&libpq.JSON{&attrs})
+//
+// If V is nil, Scan stores whatever encoding/json produces for an
+// interface{}. A JSON with a nil V is passed to the server as NULL.
+type JSON struct {
+	V interface{}
+}
+
+// Implement Valuer interface.
+func (j JSON) Value() (driver.Value, error) {
+	if j.V == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Implement Scanner interface.
+func (j *JSON) Scan(src interface{}) error {
+	var b []byte
+	switch src := src.(type) {
+	case []byte:
+		b = src
+	case string:
+		b = []byte(src)
+	case nil:
+		b = []byte("null")
+	default:
+		return errors.New("libpq: cannot scan non-JSON value into JSON")
+	}
+
+	if j.V == nil {
+		return json.Unmarshal(b, &j.V)
+	}
+	return json.Unmarshal(b, j.V)
+}
@@ -0,0 +1,62 @@
+package libpq_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func TestJSONColumns(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	for _, typ := range []string{"json", "jsonb"} {
+		var raw json.RawMessage
+		err := db.QueryRow(`select '{"a": 1}'::` + typ).Scan(&raw)
+		if err != nil {
+			t.Fatalf("Failed to Scan() %s into json.RawMessage: %s", typ, err)
+		}
+		var m map[string]int
+		if err = json.Unmarshal(raw, &m); err != nil || m["a"] != 1 {
+			t.Fatalf("Scanned invalid %s %q: %v", typ, raw, err)
+		}
+
+		var val interface{}
+		if err = db.QueryRow(`select '[1, 2]'::` + typ).Scan(&val); err != nil {
+			t.Fatalf("Failed to Scan() %s into interface{}: %s", typ, err)
+		}
+		if _, ok := val.([]byte); !ok {
+			t.Fatalf("Expected %s to be returned as []byte, got %T", typ, val)
+		}
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+	mustExec(t, db, "create temp table test (j jsonb)")
+
+	type point struct {
+		X, Y int
+	}
+	if _, err := db.Exec("insert into test(j) values($1)", libpq.JSON{V: point{3, 4}}); err != nil {
+		t.Fatalf("Failed to insert JSON value: %s", err)
+	}
+	if _, err := db.Exec("insert into test(j) values($1)", libpq.JSON{}); err != nil {
+		t.Fatalf("Failed to insert nil JSON value: %s", err)
+	}
+
+	var p point
+	if err := db.QueryRow("select j from test where j is not null").Scan(&libpq.JSON{V: &p}); err != nil {
+		t.Fatalf("Failed to Scan() into JSON: %s", err)
+	}
+	if p.X != 3 || p.Y != 4 {
+		t.Fatalf("JSON value did not round-trip (got %+v)", p)
+	}
+
+	var isNull bool
+	if err := db.QueryRow("select count(*) = 1 from test where j is null").Scan(&isNull); err != nil || !isNull {
+		t.Fatalf("nil JSON value was not stored as NULL: %v", err)
+	}
+}
@@ -44,6 +44,7 @@ type libpqListenRows struct {
 
 func (r *libpqListenRows) Close() error {
 	// we're the exclusive owners of this libpqConn, so it's safe to unlisten *
+	r.c.listens = nil
 	_, err := r.c.exec("UNLISTEN *", false)
 	return err
 }
@@ -55,11 +56,14 @@ func (r *libpqListenRows) Columns() []string {
 func (r *libpqListenRows) Next(dest []driver.Value) error {
 	// see if we already have pending notifications
 	note := C.PQnotifies(r.c.db)
-	if note == nil {
+	for note == nil {
 		// none pending - block waiting for one
 		note = C.waitForNotify(r.c.db)
 		if note == nil {
-			return driver.ErrBadConn
+			// possibly lost the connection; resetting it also re-LISTENs
+			if err := r.c.ensureConn(); err != nil {
+				return err
+			}
 		}
 	}
 	defer C.PQfreemem(unsafe.Pointer(note))
@@ -0,0 +1,140 @@
+package libpq
+
+/*
+#include <libpq-fe.h>
+*/
+import "C"
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money is the value of a money column, in minor units of the currency
+// (e.g. cents). The server formats money according to lc_monetary, so
+// rather than parse "$1,234.56" or "1.234,56 €" the driver counts the
+// digits, all of which are significant: money columns are returned as int64
+// minor units.
+//
+// Money parameters are sent in the format of the connection's lc_monetary,
+// which is determined the first time one is sent; a later SET lc_monetary
+// on the same connection is not noticed.
+type Money int64
+
+// Implement Valuer interface. The driver sends Money (and *Money) parameters,
+// array elements and composite attributes itself, in the server's money
+// format, and rejects Money range bounds; Value returns the minor units for
+// other uses.
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// Implement Scanner interface.
+func (m *Money) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case int64:
+		*m = Money(src)
+		return nil
+	case string:
+		v, err := parseMoney(src)
+		*m = Money(v)
+		return err
+	case []byte:
+		v, err := parseMoney(string(src))
+		*m = Money(v)
+		return err
+	}
+	return fmt.Errorf("libpq: cannot scan %T into Money", src)
+}
+
+// asMoney returns v as Money if it is a Money or a non-nil *Money.
+func asMoney(v interface{}) (Money, bool) {
+	switch v := v.(type) {
+	case Money:
+		return v, true
+	case *Money:
+		if v != nil {
+			return *v, true
+		}
+	}
+	return 0, false
+}
+
+// parseMoney converts the text representation of money, in any locale, to
+// minor units. Negative amounts have a '-' or are in parentheses.
+func parseMoney(s string) (int64, error) {
+	var n int64
+	digits := 0
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= '0' && c <= '9' {
+			if n > (1<<63-1-int64(c-'0'))/10 {
+				return 0, fmt.Errorf("libpq: money value %q out of range", s)
+			}
+			n = n*10 + int64(c-'0')
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0, fmt.Errorf("libpq: invalid money value %q", s)
+	}
+	if strings.ContainsAny(s, "-(") {
+		n = -n
+	}
+	return n, nil
+}
+
+// moneyFormat is how the server formats money under the session's
+// lc_monetary.
+type moneyFormat struct {
+	digits int  // fractional digits
+	point  byte // decimal point
+}
+
+// moneyFormat works out how the server formats money by looking at 1 as
+// money (e.g. "$1.00", "1,00 €" or "¥1").
+func (c *libpqConn) moneyFormat() (*moneyFormat, error) {
+	if c.money != nil {
+		return c.money, nil
+	}
+	cres, err := c.query("SELECT 1::money")
+	if err != nil {
+		return nil, err
+	}
+	s := C.GoString(C.PQgetvalue(cres, 0, 0))
+	C.PQclear(cres)
+
+	one := strings.IndexByte(s, '1')
+	if one < 0 {
+		return nil, errors.New("libpq: cannot determine money format")
+	}
+	f := &moneyFormat{point: '.'}
+	if rest := s[one+1:]; len(rest) > 1 && rest[1] == '0' {
+		f.point = rest[0]
+		f.digits = len(rest) - 1 - len(strings.TrimLeft(rest[1:], "0"))
+	}
+	c.money = f
+	return f, nil
+}
+
+// formatMoney formats m for the server, without currency symbol or
+// thousands separators.
+func (c *libpqConn) formatMoney(m Money) (string, error) {
+	f, err := c.moneyFormat()
+	if err != nil {
+		return "", err
+	}
+	s := strconv.FormatInt(int64(m), 10)
+	sign := ""
+	if m < 0 {
+		sign, s = "-", s[1:]
+	}
+	if f.digits == 0 {
+		return sign + s, nil
+	}
+	if len(s) <= f.digits {
+		s = strings.Repeat("0", f.digits-len(s)+1) + s
+	}
+	return sign + s[:len(s)-f.digits] + string(f.point) + s[len(s)-f.digits:], nil
+}
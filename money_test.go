@@ -0,0 +1,99 @@
+package libpq_test
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		text   string
+		expect libpq.Money
+	}{
+		{"$1,234.56", 123456},
+		{"-$0.05", -5},
+		{"($12.00)", -1200},
+		{"1.234,56 €", 123456},
+		{"¥1,235", 1235},
+	}
+	for _, test := range tests {
+		var m libpq.Money
+		if err := m.Scan(test.text); err != nil || m != test.expect {
+			t.Errorf("Scanned %q as %d, expected %d (%v)", test.text, m, test.expect, err)
+		}
+	}
+
+	var m libpq.Money
+	if err := m.Scan("$"); err == nil {
+		t.Errorf("Expected error scanning money without digits")
+	}
+}
+
+type testPrice struct {
+	Amount libpq.Money
+	Note   string
+}
+
+func TestMoney(t *testing.T) {
+	setup := getConn(t)
+	defer setup.Close()
+	mustExec(t, setup, "DROP TYPE IF EXISTS test_price")
+	mustExec(t, setup, "CREATE TYPE test_price AS (amount money, note text)")
+	defer setup.Exec("DROP TYPE test_price")
+	codecs := libpq.NewCodecs()
+	if err := codecs.RegisterComposite(setup, "test_price", reflect.TypeOf(testPrice{})); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, locale := range []string{"C", "de_DE.UTF-8"} {
+		c := libpq.NewConnector(testDSN())
+		c.Codecs = codecs
+		c.SessionInit = []string{"SET lc_monetary = '" + locale + "'"}
+		db := sql.OpenDB(c)
+		if err := db.Ping(); err != nil {
+			t.Logf("Skipping locale %s: %s", locale, err)
+			db.Close()
+			continue
+		}
+
+		for _, in := range []libpq.Money{123456, -5, 0} {
+			var out libpq.Money
+			var minor int64
+			if err := db.QueryRow("select $1::money, $1::money", in).Scan(&out, &minor); err != nil {
+				t.Fatalf("Failed to round-trip money in locale %s: %s", locale, err)
+			}
+			if out != in || minor != int64(in) {
+				t.Errorf("Locale %s: %d came back as %d, %d", locale, in, out, minor)
+			}
+
+			// and through a pointer, which has a Value method too
+			out = 0
+			if err := db.QueryRow("select $1::money", &in).Scan(&out); err != nil || out != in {
+				t.Errorf("Locale %s: *Money %d came back as %d (err=%v)", locale, in, out, err)
+			}
+		}
+
+		// array elements and composite attributes are sent in the server's
+		// format too
+		var first, second libpq.Money
+		err := db.QueryRow("select ($1::money[])[1], ($1::money[])[2]", []libpq.Money{150, -5}).Scan(&first, &second)
+		if err != nil || first != 150 || second != -5 {
+			t.Errorf("Locale %s: money[] came back as %d, %d (err=%v)", locale, first, second, err)
+		}
+		err = db.QueryRow("select ($1::test_price).amount", testPrice{Amount: 150, Note: "x"}).Scan(&first)
+		if err != nil || first != 150 {
+			t.Errorf("Locale %s: composite money came back as %d (err=%v)", locale, first, err)
+		}
+		db.Close()
+	}
+
+	// in range bounds Money would be sent as minor units, so it is rejected
+	_, err := setup.Exec("select $1", libpq.Range[libpq.Money]{Lower: 1, Upper: 2})
+	if err == nil || !strings.Contains(err.Error(), "unsupported range bound type Money") {
+		t.Errorf("Expected Money range bound to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package libpq
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// rewriteNamed replaces the named placeholders (@name or :name) in query with
+// positional ones ($1, $2, ...), returning the names in order of position. A
+// name used more than once gets the same position each time. String
+// literals, quoted identifiers, comments and dollar-quoted strings are left
+// alone, as are casts (::type) and operators such as @> and <@.
+// stdStrings is the server's standard_conforming_strings setting, which says
+// whether backslashes escape anything in ordinary string literals.
+//
+// If query has no named placeholders, it is returned unchanged with nil
+// names.
+func rewriteNamed(query string, stdStrings bool) (string, []string) {
+	var b strings.Builder
+	var names []string
+	last := 0 // start of the text not yet copied to b
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			escapes := !stdStrings || (i > 0 && (query[i-1] == 'E' || query[i-1] == 'e') &&
+				(i == 1 || !isIdentChar(query[i-2])))
+			i = skipQuoted(query, i, '\'', escapes)
+		case c == '"':
+			i = skipQuoted(query, i, '"', false)
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = skipComment(query, i)
+		case c == '$' && (i == 0 || !isIdentChar(query[i-1])):
+			i = skipDollarQuoted(query, i)
+		case (c == '@' || c == ':') && i+1 < len(query) && isIdentStart(query[i+1]) &&
+			(i == 0 || !isOperatorChar(query[i-1])):
+			end := i + 1
+			for end < len(query) && isIdentChar(query[end]) {
+				end++
+			}
+			name := query[i+1 : end]
+			pos := 0
+			for n, prev := range names {
+				if prev == name {
+					pos = n + 1
+				}
+			}
+			if pos == 0 {
+				names = append(names, name)
+				pos = len(names)
+			}
+			b.WriteString(query[last:i])
+			b.WriteString("$" + strconv.Itoa(pos))
+			last = end
+			i = end - 1
+		}
+	}
+
+	if names == nil {
+		return query, nil
+	}
+	b.WriteString(query[last:])
+	return b.String(), names
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
+
+func isOperatorChar(c byte) bool {
+	return strings.IndexByte("+-*/<>=~!@#%^&|`?:", c) >= 0
+}
+
+// skipQuoted returns the index of the quote closing the literal or
+// identifier opened at query[start]. Doubled quotes, and with escapes a
+// backslash, do not close it.
+func skipQuoted(query string, start int, quote byte, escapes bool) int {
+	for i := start + 1; i < len(query); i++ {
+		switch {
+		case escapes && query[i] == '\\':
+			i++
+		case query[i] == quote && i+1 < len(query) && query[i+1] == quote:
+			i++
+		case query[i] == quote:
+			return i
+		}
+	}
+	return len(query)
+}
+
+// skipComment returns the index of the end of the (possibly nested)
+// comment starting at query[start].
+func skipComment(query string, start int) int {
+	depth := 0
+	for i := start; i < len(query)-1; i++ {
+		switch query[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(query)
+}
+
+// skipDollarQuoted returns the index of the end of the dollar-quoted string
+// ($$...$$ or $tag$...$tag$) starting at query[start], or start if there
+// is none there (e.g. for a $1 placeholder).
+func skipDollarQuoted(query string, start int) int {
+	end := start + 1
+	for end < len(query) && query[end] != '$' {
+		if !isIdentChar(query[end]) || (end == start+1 && !isIdentStart(query[end])) {
+			return start
+		}
+		end++
+	}
+	if end >= len(query) {
+		return start
+	}
+	tag := query[start : end+1]
+	if close := strings.Index(query[end+1:], tag); close >= 0 {
+		return end + close + len(tag)
+	}
+	return len(query)
+}
+
+// namedKeyPrefix marks the statement cache keys of queries whose named
+// placeholders were rewritten. Queries can't contain NUL bytes, so these
+// keys never collide with those of queries prepared as written.
+const namedKeyPrefix = "\x00named\x00"
+
+// standardConformingStrings reports the server's standard_conforming_strings
+// setting, which is on by default since 9.1.
+func (c *libpqConn) standardConformingStrings() bool {
+	v, ok := c.ParameterStatus("standard_conforming_strings")
+	return !ok || v == "on"
+}
+
+// bindArgs orders args for a statement, which takes the named parameters in
+// names if it has any.
+func bindArgs(names []string, args []driver.NamedValue) ([]driver.Value, error) {
+	if names == nil {
+		values := make([]driver.Value, len(args))
+		for i, arg := range args {
+			if arg.Name != "" {
+				return nil, fmt.Errorf("libpq: named parameter @%s, but the query has no named placeholders", arg.Name)
+			}
+			values[i] = arg.Value
+		}
+		return values, nil
+	}
+
+	values := make([]driver.Value, len(names))
+	for i, name := range names {
+		found := false
+		for _, arg := range args {
+			if arg.Name == "" {
+				return nil, fmt.Errorf("libpq: parameter $%d is positional, but the query uses named placeholders", arg.Ordinal)
+			}
+			if arg.Name == name {
+				values[i], found = arg.Value, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("libpq: missing value for named parameter @%s", name)
+		}
+	}
+	return values, nil
+}
+
+func hasNamed(args []driver.NamedValue) bool {
+	for _, arg := range args {
+		if arg.Name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Implement ConnPrepareContext interface.
+func (c *libpqConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Prepare(query)
+}
+
+// Implement ExecerContext interface. Queries are only rewritten when given
+// named parameters, going through the statement cache where the rewrite is
+// kept; others are sent as written.
+func (c *libpqConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !hasNamed(args) {
+		values, _ := bindArgs(nil, args)
+		return c.Exec(query, values)
+	}
+	stmt, err := c.prepareNamed(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args)
+}
+
+// Implement QueryerContext interface.
+func (c *libpqConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !hasNamed(args) {
+		stmt, err := c.Prepare(query)
+		if err != nil {
+			return nil, err
+		}
+		return stmt.(*libpqStmt).QueryContext(ctx, args)
+	}
+	stmt, err := c.prepareNamed(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args)
+}
+
+// Implement StmtExecContext interface.
+func (s *libpqStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	values, err := bindArgs(s.names, args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Exec(values)
+}
+
+// Implement StmtQueryContext interface.
+func (s *libpqStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	values, err := bindArgs(s.names, args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(values)
+}
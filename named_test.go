@@ -0,0 +1,92 @@
+package libpq_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestNamedParams(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	tests := []struct {
+		query  string
+		args   []interface{}
+		expect string
+	}{
+		{"select @a::text || :b::text || @a::text",
+			[]interface{}{sql.Named("a", "x"), sql.Named("b", "y")}, "xyx"},
+		{"select '@a :b' || @a::text -- :b\n",
+			[]interface{}{sql.Named("a", "!")}, "@a :b!"},
+		{`select /* @a /* :b */ */ "?column?" from (select @a::text) s`,
+			[]interface{}{sql.Named("a", "z")}, "z"},
+		{"select $$ @a $$ || $tag$ :b $tag$ || @a::text",
+			[]interface{}{sql.Named("a", "1")}, " @a  :b 1"},
+		{`select E'\' @a' || @a::text`,
+			[]interface{}{sql.Named("a", "2")}, "' @a2"},
+		{"select (array[1,2,3] @> array[@a::int])::text",
+			[]interface{}{sql.Named("a", 2)}, "true"},
+	}
+	for _, test := range tests {
+		var s string
+		if err := db.QueryRow(test.query, test.args...).Scan(&s); err != nil {
+			t.Errorf("%q failed: %s", test.query, err)
+			continue
+		}
+		if s != test.expect {
+			t.Errorf("%q: got %q, expected %q", test.query, s, test.expect)
+		}
+	}
+
+	mustExec(t, db, "DROP TABLE IF EXISTS test")
+	mustExec(t, db, "CREATE TABLE test (a int, b text)")
+	defer db.Exec("DROP TABLE test")
+	res, err := db.Exec("insert into test values (@a, @b), (@a + 1, @b)", sql.Named("b", "s"), sql.Named("a", 1))
+	if err != nil {
+		t.Fatalf("Failed to Exec() with named parameters: %s", err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Errorf("Expected 2 rows affected, got %d", n)
+	}
+
+	var n int
+	if err := db.QueryRow("select count(*) from test where a >= :min and b = :b",
+		sql.Named("min", 2), sql.Named("b", "s")).Scan(&n); err != nil || n != 1 {
+		t.Errorf("Query with named parameters: %v (got %d)", err, n)
+	}
+
+	_, err = db.Exec("select @a::int", sql.Named("b", 1))
+	if err == nil || !strings.Contains(err.Error(), "missing value for named parameter @a") {
+		t.Errorf("Expected missing parameter error, got %v", err)
+	}
+}
+
+func TestUnnamedParamsNotRewritten(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	// without named parameters, queries are sent as written, so array slices
+	// and the prefix @ (absolute value) operator keep their meaning
+	for _, test := range []struct{ query, expect string }{
+		{"select ((array[1,2,3])[1:n])::text from (select $1::int as n) s", "{1,2}"},
+		{"select (@x)::text from (select -$1::int as x) s", "2"},
+	} {
+		query := test.query
+		var s string
+		if err := db.QueryRow(query, 2).Scan(&s); err != nil || s != test.expect {
+			t.Errorf("%q: got %q, expected %q (err=%v)", query, s, test.expect, err)
+		}
+
+		stmt, err := db.Prepare(query)
+		if err != nil {
+			t.Errorf("Failed to Prepare() %q: %s", query, err)
+			continue
+		}
+		var p string
+		if err := stmt.QueryRow(2).Scan(&p); err != nil || p != test.expect {
+			t.Errorf("Prepared %q: got %q, expected %q (err=%v)", query, p, test.expect, err)
+		}
+		stmt.Close()
+	}
+}
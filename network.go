@@ -0,0 +1,193 @@
+package libpq
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Addr is the value of an inet column, without its netmask. Scanning a
+// cidr or an inet with a netmask yields the address part.
+//
+// netip.Addr (and net.IP) values can be passed as parameters directly.
+type Addr struct {
+	netip.Addr
+}
+
+// Prefix is the value of an inet or cidr column, with its netmask. An inet
+// without a netmask is scanned as a single-address prefix.
+//
+// netip.Prefix (and *net.IPNet) values can be passed as parameters directly.
+type Prefix struct {
+	netip.Prefix
+}
+
+// HardwareAddr is the value of a macaddr or macaddr8 column.
+//
+// net.HardwareAddr values can be passed as parameters directly.
+type HardwareAddr net.HardwareAddr
+
+func parseAddr(s string) (netip.Addr, error) {
+	if strings.IndexByte(s, '/') >= 0 {
+		p, err := netip.ParsePrefix(s)
+		return p.Addr(), err
+	}
+	return netip.ParseAddr(s)
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.IndexByte(s, '/') < 0 {
+		a, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(a, a.BitLen()), nil
+	}
+	return netip.ParsePrefix(s)
+}
+
+// Implement Valuer interface.
+func (a Addr) Value() (driver.Value, error) {
+	if !a.IsValid() {
+		return nil, nil
+	}
+	return a.String(), nil
+}
+
+// Implement Scanner interface.
+func (a *Addr) Scan(src interface{}) error {
+	s, null, err := srcText(src, "Addr")
+	if err != nil || null {
+		a.Addr = netip.Addr{}
+		return err
+	}
+	a.Addr, err = parseAddr(s)
+	return err
+}
+
+// Implement Valuer interface.
+func (p Prefix) Value() (driver.Value, error) {
+	if !p.IsValid() {
+		return nil, nil
+	}
+	return p.String(), nil
+}
+
+// Implement Scanner interface.
+func (p *Prefix) Scan(src interface{}) error {
+	s, null, err := srcText(src, "Prefix")
+	if err != nil || null {
+		p.Prefix = netip.Prefix{}
+		return err
+	}
+	p.Prefix, err = parsePrefix(s)
+	return err
+}
+
+func (h HardwareAddr) String() string {
+	return net.HardwareAddr(h).String()
+}
+
+// Implement Valuer interface.
+func (h HardwareAddr) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	return h.String(), nil
+}
+
+// Implement Scanner interface.
+func (h *HardwareAddr) Scan(src interface{}) error {
+	s, null, err := srcText(src, "HardwareAddr")
+	if err != nil || null {
+		*h = nil
+		return err
+	}
+	mac, err := net.ParseMAC(s)
+	*h = HardwareAddr(mac)
+	return err
+}
+
+// AddrArray is the value of an inet[] column.
+type AddrArray []netip.Addr
+
+// Implement Valuer interface.
+func (a AddrArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return valueArray(a, netip.Addr.String), nil
+}
+
+// Implement Scanner interface.
+func (a *AddrArray) Scan(src interface{}) error {
+	arr, err := scanArray(src, "AddrArray", parseAddr)
+	*a = arr
+	return err
+}
+
+// PrefixArray is the value of an inet[] or cidr[] column.
+type PrefixArray []netip.Prefix
+
+// Implement Valuer interface.
+func (a PrefixArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return valueArray(a, netip.Prefix.String), nil
+}
+
+// Implement Scanner interface.
+func (a *PrefixArray) Scan(src interface{}) error {
+	arr, err := scanArray(src, "PrefixArray", parsePrefix)
+	*a = arr
+	return err
+}
+
+// HardwareAddrArray is the value of a macaddr[] or macaddr8[] column.
+type HardwareAddrArray []net.HardwareAddr
+
+// Implement Valuer interface.
+func (a HardwareAddrArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return valueArray(a, net.HardwareAddr.String), nil
+}
+
+// Implement Scanner interface.
+func (a *HardwareAddrArray) Scan(src interface{}) error {
+	arr, err := scanArray(src, "HardwareAddrArray", net.ParseMAC)
+	*a = arr
+	return err
+}
+
+// encodeNetwork returns the text representation of the network parameter v,
+// if it is one. They are sent untyped, so that the server can pick inet or
+// cidr (or macaddr or macaddr8) from context.
+func encodeNetwork(v interface{}) (string, bool, error) {
+	switch v := v.(type) {
+	case netip.Addr:
+		return v.String(), true, nil
+	case netip.Prefix:
+		return v.String(), true, nil
+	case net.HardwareAddr:
+		return v.String(), true, nil
+	case net.IP:
+		if v.To16() == nil {
+			return "", true, fmt.Errorf("libpq: invalid IP address %v", []byte(v))
+		}
+		return v.String(), true, nil
+	case *net.IPNet:
+		return v.String(), true, nil
+	case []netip.Addr:
+		return valueArray(v, netip.Addr.String), true, nil
+	case []netip.Prefix:
+		return valueArray(v, netip.Prefix.String), true, nil
+	case []net.HardwareAddr:
+		return valueArray(v, net.HardwareAddr.String), true, nil
+	}
+	return "", false, nil
+}
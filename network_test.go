@@ -0,0 +1,98 @@
+package libpq_test
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func TestNetworkScan(t *testing.T) {
+	var a libpq.Addr
+	if err := a.Scan("192.168.1.5/24"); err != nil || a.Addr != netip.MustParseAddr("192.168.1.5") {
+		t.Errorf("Failed to scan inet with netmask into Addr: %v (got %s)", err, a)
+	}
+
+	var p libpq.Prefix
+	if err := p.Scan("2001:db8::1"); err != nil || p.Prefix != netip.MustParsePrefix("2001:db8::1/128") {
+		t.Errorf("Failed to scan inet without netmask into Prefix: %v (got %s)", err, p)
+	}
+
+	var h libpq.HardwareAddr
+	if err := h.Scan([]byte("08:00:2b:01:02:03:04:05")); err != nil || len(h) != 8 {
+		t.Errorf("Failed to scan macaddr8 into HardwareAddr: %v (got %s)", err, h)
+	}
+
+	if err := a.Scan(nil); err != nil || a.IsValid() {
+		t.Errorf("Failed to scan NULL into Addr: %v", err)
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+	mustExec(t, db, "create temp table test (i inet, c cidr, m macaddr, m8 macaddr8)")
+
+	addr := netip.MustParseAddr("10.1.2.3")
+	prefix := netip.MustParsePrefix("10.1.0.0/16")
+	mac, _ := net.ParseMAC("08:00:2b:01:02:03")
+	mac8, _ := net.ParseMAC("08:00:2b:01:02:03:04:05")
+	_, err := db.Exec("insert into test values($1, $2, $3, $4)", addr, prefix, mac, mac8)
+	if err != nil {
+		t.Fatalf("Failed to insert network values: %s", err)
+	}
+	_, err = db.Exec("insert into test values($1, $2, $3, $4)", net.ParseIP("10.1.2.3"),
+		&net.IPNet{IP: net.IPv4(10, 1, 0, 0), Mask: net.CIDRMask(16, 32)}, libpq.HardwareAddr(mac), libpq.HardwareAddr(mac8))
+	if err != nil {
+		t.Fatalf("Failed to insert net package values: %s", err)
+	}
+
+	rows, err := db.Query("select i, c, i, m, m8 from test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i libpq.Addr
+		var c, ip libpq.Prefix
+		var m, m8 libpq.HardwareAddr
+		mustScan(t, rows, &i, &c, &ip, &m, &m8)
+		if i.Addr != addr || c.Prefix != prefix || ip.Prefix != netip.PrefixFrom(addr, 32) {
+			t.Errorf("Unexpected inet/cidr values %s, %s, %s", i, c, ip)
+		}
+		if m.String() != mac.String() || m8.String() != mac8.String() {
+			t.Errorf("Unexpected macaddr values %s, %s", m, m8)
+		}
+	}
+}
+
+func TestNetworkArrays(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	addrs := []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")}
+	var gotAddrs libpq.AddrArray
+	if err := db.QueryRow("select $1::inet[]", addrs).Scan(&gotAddrs); err != nil {
+		t.Fatalf("Failed to Scan() inet[]: %s", err)
+	}
+	if len(gotAddrs) != 2 || gotAddrs[0] != addrs[0] || gotAddrs[1] != addrs[1] {
+		t.Errorf("Unexpected inet[] value %v", gotAddrs)
+	}
+
+	var prefixes libpq.PrefixArray
+	if err := db.QueryRow("select '{10.0.0.0/8,192.168.0.0/16}'::cidr[]").Scan(&prefixes); err != nil {
+		t.Fatalf("Failed to Scan() cidr[]: %s", err)
+	}
+	if len(prefixes) != 2 || prefixes[1] != netip.MustParsePrefix("192.168.0.0/16") {
+		t.Errorf("Unexpected cidr[] value %v", prefixes)
+	}
+
+	var macs libpq.HardwareAddrArray
+	if err := db.QueryRow("select $1::macaddr[]", libpq.HardwareAddrArray{net.HardwareAddr{1, 2, 3, 4, 5, 6}}).Scan(&macs); err != nil {
+		t.Fatalf("Failed to Scan() macaddr[]: %s", err)
+	}
+	if len(macs) != 1 || macs[0].String() != "01:02:03:04:05:06" {
+		t.Errorf("Unexpected macaddr[] value %v", macs)
+	}
+}
@@ -0,0 +1,304 @@
+package libpq
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ParamEncoder is implemented by parameter types that encode themselves for
+// the driver. EncodeParam returns the text representation of the value (nil
+// for NULL) and the OID of the type the server should take it as, or 0 to
+// let the server infer the type from context.
+type ParamEncoder interface {
+	EncodeParam() (text []byte, typeOid uint32, err error)
+}
+
+// Implement NamedValueChecker interface. Parameter types that buildCArgs
+// knows how to send go through untouched; pointers are dereferenced, and
+// slices and maps become arrays and hstores. Anything else is converted as
+// database/sql would, or rejected.
+func (c *libpqConn) CheckNamedValue(nv *driver.NamedValue) error {
+	v, err := c.checkValue(nv.Value)
+	if err != nil {
+		return fmt.Errorf("libpq: parameter $%d: %s", nv.Ordinal, err)
+	}
+	nv.Value = v
+	return nil
+}
+
+func (c *libpqConn) checkValue(v interface{}) (interface{}, error) {
+	if isNull(v) {
+		return nil, nil
+	}
+	switch v := v.(type) {
+	case nil, int64, float64, bool, []byte, string, time.Time,
+		UUID, [16]byte, UUIDArray, []UUID, Interval, time.Duration, Money,
+		netip.Addr, netip.Prefix, net.HardwareAddr, net.IP, *net.IPNet,
+		[]netip.Addr, []netip.Prefix, []net.HardwareAddr,
+		map[string]*string, map[string]string:
+		return v, nil
+	case Hstore:
+		return v, nil
+	case *Money:
+		// not through Value, which gives minor units
+		if v == nil {
+			return nil, nil
+		}
+		return *v, nil
+	case *big.Int, *big.Float, *big.Rat:
+		if reflect.ValueOf(v).IsNil() {
+			return nil, nil
+		}
+		_, err := encodeBig(v)
+		return v, err
+	case ParamEncoder:
+		return v, nil
+	}
+	if c.codecs.forValue(v) != nil {
+		// nil slices, maps and pointers are NULL, as they are for other types
+		switch rv := reflect.ValueOf(v); rv.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Ptr:
+			if rv.IsNil() {
+				return nil, nil
+			}
+		}
+		return v, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if _, ok := v.(driver.Valuer); !ok && rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return c.checkValue(rv.Elem().Interface())
+	}
+
+	if dv, err := driver.DefaultParameterConverter.ConvertValue(v); err == nil {
+		return dv, nil
+	} else if _, ok := v.(driver.Valuer); ok {
+		return nil, err
+	}
+
+	switch rv.Kind() {
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// e.g. a [32]byte hash, sent as bytea
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return b, nil
+		}
+		return c.encodeArray(rv)
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return c.encodeArray(rv)
+	case reflect.Map:
+		if h, ok := toHstore(rv); ok {
+			if h == nil {
+				return nil, nil
+			}
+			return h, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %T", v)
+}
+
+// isNull reports whether v, of a type buildCArgs sends itself, stands for
+// NULL. database/sql does not call Value for drivers that check their own
+// parameters, so this follows the types' Value methods (or, for the standard
+// library types, those of their wrappers, e.g. Addr and Hstore). A nil
+// []byte is NULL, as it is to database/sql.
+func isNull(v interface{}) bool {
+	switch v := v.(type) {
+	case []byte:
+		return v == nil
+	case Hstore:
+		return v == nil
+	case map[string]string:
+		return v == nil
+	case map[string]*string:
+		return v == nil
+	case UUIDArray:
+		return v == nil
+	case []UUID:
+		return v == nil
+	case netip.Addr:
+		return !v.IsValid()
+	case netip.Prefix:
+		return !v.IsValid()
+	case net.HardwareAddr:
+		return v == nil
+	case net.IP:
+		return v == nil
+	case *net.IPNet:
+		return v == nil
+	case []netip.Addr:
+		return v == nil
+	case []netip.Prefix:
+		return v == nil
+	case []net.HardwareAddr:
+		return v == nil
+	}
+	return false
+}
+
+// encodeArray returns the text representation of a one-dimensional array
+// holding the elements of rv, which is sent untyped.
+func (c *libpqConn) encodeArray(rv reflect.Value) (string, error) {
+	elems := make([]*string, rv.Len())
+	for i := range elems {
+		e := rv.Index(i)
+		if k := e.Kind(); (k == reflect.Slice || k == reflect.Array) && e.Type().Elem().Kind() != reflect.Uint8 {
+			return "", fmt.Errorf("unsupported type %s: multidimensional arrays are not supported", rv.Type())
+		}
+		s, null, err := encodeElement(c, c.codecs, e.Interface())
+		if err != nil {
+			return "", fmt.Errorf("element %d: %s", i, err)
+		}
+		if !null {
+			elems[i] = &s
+		}
+	}
+	return formatArray(elems, ','), nil
+}
+
+// toHstore converts a map with string keys and string or *string values.
+func toHstore(rv reflect.Value) (Hstore, bool) {
+	t := rv.Type()
+	if t.Key().Kind() != reflect.String {
+		return nil, false
+	}
+	ptr := t.Elem().Kind() == reflect.Ptr
+	if !(t.Elem().Kind() == reflect.String || ptr && t.Elem().Elem().Kind() == reflect.String) {
+		return nil, false
+	}
+	if rv.IsNil() {
+		return nil, true
+	}
+	h := make(Hstore, rv.Len())
+	for it := rv.MapRange(); it.Next(); {
+		val := it.Value()
+		if ptr {
+			if val.IsNil() {
+				h[it.Key().String()] = nil
+				continue
+			}
+			val = val.Elem()
+		}
+		s := val.String()
+		h[it.Key().String()] = &s
+	}
+	return h, true
+}
+
+// encodeBig returns the numeric representation of a *big.Int, *big.Float or
+// *big.Rat. Rats must have a terminating decimal expansion.
+func encodeBig(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case *big.Int:
+		return v.String(), nil
+	case *big.Float:
+		if v.IsInf() {
+			return formatFloat(math.Inf(v.Sign())), nil
+		}
+		return v.Text('g', -1), nil
+	case *big.Rat:
+		// 1/(2^a 5^b) needs max(a, b) decimal places
+		d := new(big.Int).Set(v.Denom())
+		places := 0
+		for _, f := range []int64{2, 5} {
+			n := 0
+			for m := new(big.Int); ; n++ {
+				q, r := new(big.Int).QuoRem(d, big.NewInt(f), m)
+				if r.Sign() != 0 {
+					break
+				}
+				d = q
+			}
+			if n > places {
+				places = n
+			}
+		}
+		if d.Cmp(big.NewInt(1)) != 0 {
+			return "", fmt.Errorf("%s has no exact decimal representation", v)
+		}
+		return v.FloatString(places), nil
+	}
+	return "", fmt.Errorf("unsupported type %T", v)
+}
+
+// encodeElement returns the text representation of v as an array element or
+// composite attribute, using codecs for types registered there. Money is
+// formatted for the server c is connected to, so it can't be encoded if c is
+// nil.
+func encodeElement(c *libpqConn, codecs *Codecs, v interface{}) (s string, null bool, err error) {
+	if ref := codecs.forValue(v); ref != nil && ref.codec.EncodeText != nil {
+		b, err := c.encodeText(ref.codec, v)
+		return string(b), false, err
+	}
+	if d, ok := v.(time.Duration); ok {
+		return Interval{Microseconds: d.Microseconds()}.String(), false, nil
+	}
+	if s, ok, err := encodeNetwork(v); ok {
+		return s, false, err
+	}
+	if s, ok := encodeHstore(v); ok {
+		return s, false, nil
+	}
+	if m, ok := asMoney(v); ok {
+		// its Value is minor units, which the server would read as major
+		// ones
+		if c == nil {
+			return "", false, errors.New("cannot encode Money without a connection")
+		}
+		s, err := c.formatMoney(m)
+		return s, false, err
+	}
+	if e, ok := v.(ParamEncoder); ok {
+		b, _, err := e.EncodeParam()
+		return string(b), b == nil, err
+	}
+	switch v.(type) {
+	case *big.Int, *big.Float, *big.Rat:
+		if reflect.ValueOf(v).IsNil() {
+			return "", true, nil
+		}
+		s, err := encodeBig(v)
+		return s, false, err
+	}
+
+	dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		return "", false, err
+	}
+	switch dv := dv.(type) {
+	case nil:
+		return "", true, nil
+	case int64:
+		return strconv.FormatInt(dv, 10), false, nil
+	case float64:
+		return formatFloat(dv), false, nil
+	case []byte:
+		return `\x` + hex.EncodeToString(dv), false, nil
+	case string:
+		return dv, false, nil
+	case time.Time:
+		return dv.Format(timeFormat), false, nil
+	case bool:
+		if dv {
+			return "t", false, nil
+		}
+		return "f", false, nil
+	}
+	return fmt.Sprint(dv), false, nil
+}
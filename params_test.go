@@ -0,0 +1,81 @@
+package libpq_test
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+type testPercent float64
+
+func (p testPercent) EncodeParam() ([]byte, uint32, error) {
+	return []byte(big.NewFloat(float64(p)/100).Text('f', -1)), 1700, nil
+}
+
+func TestParamTypes(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	one, two := 1, 2
+	u, _ := libpq.ParseUUID(testUUID)
+	tests := []struct {
+		query  string
+		param  interface{}
+		expect string
+	}{
+		{"select $1::int[]", []int{1, 2, 3}, "{1,2,3}"},
+		{"select $1::text[]", []string{`a "b"`, `c\d`, ""}, `{"a \"b\"","c\\d",""}`},
+		{"select $1::int[]", []*int{&one, nil, &two}, "{1,NULL,2}"},
+		{"select $1::float8[]", []float32{1.5, -2}, "{1.5,-2}"},
+		{"select $1::bool[]", [2]bool{true, false}, "{t,f}"},
+		{"select $1::bytea[]", [][]byte{{1, 2}}, `{"\\x0102"}`},
+		{"select ($1::hstore) -> 'k'", map[string]string{"k": "v"}, "v"},
+		{"select $1::numeric", new(big.Int).Lsh(big.NewInt(1), 100), "1267650600228229401496703205376"},
+		{"select $1::numeric", big.NewFloat(2.5), "2.5"},
+		{"select $1::numeric", big.NewRat(1, 8), "0.125"},
+		{"select $1::text", &u, testUUID},
+		{"select $1::text", &one, "1"},
+		{"select $1 * 200", testPercent(7), "14.00"},
+		{"select ($1::uuid[] is null)::text", libpq.UUIDArray(nil), "true"},
+		{"select ($1::uuid[] is null)::text", []libpq.UUID(nil), "true"},
+		{"select ($1::int[] is null)::text", []int(nil), "true"},
+		{"select ($1::inet is null)::text", netip.Addr{}, "true"},
+		{"select ($1::inet[] is null)::text", []netip.Addr(nil), "true"},
+		{"select ($1::macaddr is null)::text", net.HardwareAddr(nil), "true"},
+	}
+	for _, test := range tests {
+		var s string
+		if err := db.QueryRow(test.query, test.param).Scan(&s); err != nil {
+			if strings.Contains(err.Error(), `type "hstore" does not exist`) {
+				continue
+			}
+			t.Errorf("%s with %T failed: %s", test.query, test.param, err)
+			continue
+		}
+		if s != test.expect {
+			t.Errorf("%s with %T: got %q, expected %q", test.query, test.param, s, test.expect)
+		}
+	}
+
+	errTests := []struct {
+		param  interface{}
+		expect string
+	}{
+		{struct{}{}, "libpq: parameter $2: unsupported type struct {}"},
+		{big.NewRat(1, 3), "libpq: parameter $2: 1/3 has no exact decimal representation"},
+		{[][]int{{1}}, "multidimensional arrays are not supported"},
+		{map[int]string{}, "libpq: parameter $2: unsupported type map[int]string"},
+		{"a\x00b", "libpq: parameter $2: text contains a NUL byte"},
+		{[]string{"a\x00b"}, "libpq: parameter $2: text contains a NUL byte"},
+	}
+	for _, test := range errTests {
+		_, err := db.Exec("select $1, $2", 1, test.param)
+		if err == nil || !strings.Contains(err.Error(), test.expect) {
+			t.Errorf("Expected error %q for %T, got %v", test.expect, test.param, err)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package libpq
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BoundType says whether a bound of a Range includes its value, or whether
+// there is no bound on that side at all.
+type BoundType int
+
+const (
+	Inclusive BoundType = iota
+	Exclusive
+	Unbounded
+)
+
+// Range is the value of a range column (int4range, int8range, numrange,
+// tsrange, tstzrange, daterange, or a user-defined range type). T may be
+// int32, int64, int, float64, string, time.Time, or any type whose pointer
+// implements sql.Scanner and which implements driver.Valuer; string is the
+// lossless choice for numrange.
+//
+// Ranges passed as parameters are sent untyped, so the server takes the
+// range type from context.
+//
+// time.Time has no infinity, so infinity and -infinity bounds (as in
+// '[2020-01-01,infinity)') are scanned as Unbounded. Postgres tells the two
+// apart, e.g. only a range with an inclusive infinity bound contains
+// 'infinity', but this is rarely relied on.
+type Range[T any] struct {
+	Lower, Upper         T
+	LowerType, UpperType BoundType
+	Empty                bool
+}
+
+var errRangeFormat = errors.New("libpq: invalid range format")
+
+// Implement Valuer interface.
+func (r Range[T]) Value() (driver.Value, error) {
+	var b strings.Builder
+	if err := r.format(&b); err != nil {
+		return nil, err
+	}
+	return b.String(), nil
+}
+
+// Implement Scanner interface.
+func (r *Range[T]) Scan(src interface{}) error {
+	s, null, err := srcText(src, "Range")
+	if err != nil {
+		return err
+	}
+	if null {
+		return errors.New("libpq: cannot scan NULL into Range")
+	}
+	rest, err := r.parse(s)
+	if err == nil && strings.TrimSpace(rest) != "" {
+		err = errRangeFormat
+	}
+	return err
+}
+
+func (r Range[T]) format(b *strings.Builder) error {
+	if r.Empty {
+		b.WriteString("empty")
+		return nil
+	}
+	if r.LowerType == Inclusive {
+		b.WriteByte('[')
+	} else {
+		b.WriteByte('(')
+	}
+	if r.LowerType != Unbounded {
+		if err := formatBound(b, r.Lower); err != nil {
+			return err
+		}
+	}
+	b.WriteByte(',')
+	if r.UpperType != Unbounded {
+		if err := formatBound(b, r.Upper); err != nil {
+			return err
+		}
+	}
+	if r.UpperType == Inclusive {
+		b.WriteByte(']')
+	} else {
+		b.WriteByte(')')
+	}
+	return nil
+}
+
+// parse sets r from the range at the start of s, returning the rest of s.
+func (r *Range[T]) parse(s string) (string, error) {
+	*r = Range[T]{}
+	s = strings.TrimLeft(s, " ")
+	if len(s) >= 5 && strings.EqualFold(s[:5], "empty") {
+		r.Empty = true
+		return s[5:], nil
+	}
+	if s == "" || (s[0] != '[' && s[0] != '(') {
+		return s, errRangeFormat
+	}
+	if s[0] == '(' {
+		r.LowerType = Exclusive
+	}
+
+	lower, ok, s, err := scanElement(s[1:], ",])")
+	if err != nil {
+		return s, err
+	}
+	if s == "" || s[0] != ',' {
+		return s, errRangeFormat
+	}
+	upper, uok, s, err := scanElement(s[1:], ",])")
+	if err != nil {
+		return s, err
+	}
+	if s == "" || (s[0] != ']' && s[0] != ')') {
+		return s, errRangeFormat
+	}
+	if s[0] == ')' {
+		r.UpperType = Exclusive
+	}
+
+	if !ok || infiniteBound[T](lower) {
+		r.LowerType = Unbounded
+	} else if r.Lower, err = parseBound[T](lower); err != nil {
+		return s, err
+	}
+	if !uok || infiniteBound[T](upper) {
+		r.UpperType = Unbounded
+	} else if r.Upper, err = parseBound[T](upper); err != nil {
+		return s, err
+	}
+	return s[1:], nil
+}
+
+// scanElement reads a range bound or composite attribute up to the first
+// unquoted character in stops, undoing quoting. ok is false if the element
+// is missing (an infinite bound or a NULL attribute).
+func scanElement(s, stops string) (elem string, ok bool, rest string, err error) {
+	var b strings.Builder
+	i := 0
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case strings.IndexByte(stops, c) >= 0:
+			return b.String(), ok, s[i:], nil
+		case c == '\\':
+			i++
+			if i >= len(s) {
+				return "", false, s, errRangeFormat
+			}
+			b.WriteByte(s[i])
+		case c == '"':
+			// quoted section; "" and \ escape a quote
+			for i++; ; i++ {
+				if i >= len(s) {
+					return "", false, s, errRangeFormat
+				}
+				if s[i] == '\\' || (s[i] == '"' && i+1 < len(s) && s[i+1] == '"') {
+					i++
+				} else if s[i] == '"' {
+					break
+				}
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+		ok = true
+	}
+	return "", false, s, errRangeFormat
+}
+
+// infiniteBound reports whether s is an infinite time bound, which is scanned
+// as Unbounded.
+func infiniteBound[T any](s string) bool {
+	var v T
+	if _, ok := interface{}(v).(time.Time); !ok {
+		return false
+	}
+	return s == "infinity" || s == "-infinity"
+}
+
+func parseBound[T any](s string) (T, error) {
+	var v T
+	var err error
+	switch p := interface{}(&v).(type) {
+	case *int32:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 32)
+		*p = int32(n)
+	case *int64:
+		*p, err = strconv.ParseInt(s, 10, 64)
+	case *int:
+		*p, err = strconv.Atoi(s)
+	case *float64:
+		*p, err = strconv.ParseFloat(s, 64)
+	case *string:
+		*p = s
+	case *time.Time:
+		*p, err = parseDateOrTimestamp(s)
+	case sql.Scanner:
+		err = p.Scan(s)
+	default:
+		err = fmt.Errorf("libpq: unsupported range bound type %T", v)
+	}
+	return v, err
+}
+
+func formatBound[T any](b *strings.Builder, v T) error {
+	var s string
+	switch v := interface{}(v).(type) {
+	case int32:
+		s = strconv.FormatInt(int64(v), 10)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case int:
+		s = strconv.Itoa(v)
+	case float64:
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		s = v
+	case time.Time:
+		s = v.Format(timeFormat)
+	case Money:
+		// there are no money ranges, and Value would give minor units
+		return errors.New("libpq: unsupported range bound type Money")
+	case driver.Valuer:
+		val, err := v.Value()
+		if err != nil {
+			return err
+		}
+		switch val := val.(type) {
+		case string:
+			s = val
+		case []byte:
+			s = string(val)
+		default:
+			return fmt.Errorf("libpq: unsupported range bound value %T", val)
+		}
+	default:
+		return fmt.Errorf("libpq: unsupported range bound type %T", v)
+	}
+
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+	return nil
+}
+
+// Multirange is the value of a multirange column (Postgres 14 and later),
+// e.g. int4multirange. T is as for Range.
+type Multirange[T any] []Range[T]
+
+// Implement Valuer interface.
+func (m Multirange[T]) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, r := range m {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := r.format(&b); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+// Implement Scanner interface.
+func (m *Multirange[T]) Scan(src interface{}) error {
+	s, null, err := srcText(src, "Multirange")
+	if err != nil || null {
+		*m = nil
+		return err
+	}
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return errRangeFormat
+	}
+	s = s[1 : len(s)-1]
+
+	mr := Multirange[T]{}
+	for strings.TrimSpace(s) != "" {
+		var r Range[T]
+		if s, err = r.parse(s); err != nil {
+			return err
+		}
+		mr = append(mr, r)
+		s = strings.TrimLeft(s, " ")
+		if s != "" {
+			if s[0] != ',' {
+				return errRangeFormat
+			}
+			s = s[1:]
+		}
+	}
+	*m = mr
+	return nil
+}
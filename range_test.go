@@ -0,0 +1,172 @@
+package libpq_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+var int4RangeTests = []struct {
+	text   string
+	expect libpq.Range[int32]
+}{
+	{"empty", libpq.Range[int32]{Empty: true}},
+	{"[1,5)", libpq.Range[int32]{Lower: 1, Upper: 5, UpperType: libpq.Exclusive}},
+	{"(1,5]", libpq.Range[int32]{Lower: 1, Upper: 5, LowerType: libpq.Exclusive}},
+	{"(,5)", libpq.Range[int32]{Upper: 5, LowerType: libpq.Unbounded, UpperType: libpq.Exclusive}},
+	{"[-3,)", libpq.Range[int32]{Lower: -3, UpperType: libpq.Unbounded}},
+	{"(,)", libpq.Range[int32]{LowerType: libpq.Unbounded, UpperType: libpq.Unbounded}},
+	{`["1","5"]`, libpq.Range[int32]{Lower: 1, Upper: 5}},
+}
+
+func TestRangeScan(t *testing.T) {
+	for _, test := range int4RangeTests {
+		var r libpq.Range[int32]
+		if err := r.Scan(test.text); err != nil {
+			t.Errorf("Failed to Scan() %q: %s", test.text, err)
+			continue
+		}
+		if r != test.expect {
+			t.Errorf("Scanned %q as %+v, expected %+v", test.text, r, test.expect)
+		}
+
+		v, err := r.Value()
+		if err != nil {
+			t.Errorf("Value() of %+v failed: %s", r, err)
+			continue
+		}
+		var back libpq.Range[int32]
+		if err := back.Scan(v); err != nil || back != r {
+			t.Errorf("%+v did not round-trip through %q: %v", r, v, err)
+		}
+	}
+
+	// quoting of string bounds
+	var s libpq.Range[string]
+	if err := s.Scan(`["a,\"b","c""d\\")`); err != nil {
+		t.Fatalf("Failed to Scan() quoted bounds: %s", err)
+	}
+	if s.Lower != `a,"b` || s.Upper != `c"d\` {
+		t.Errorf("Unexpected quoted bounds %q, %q", s.Lower, s.Upper)
+	}
+	v, _ := s.Value()
+	var back libpq.Range[string]
+	if err := back.Scan(v); err != nil || back != s {
+		t.Errorf("%+v did not round-trip through %q: %v", s, v, err)
+	}
+
+	var tr libpq.Range[time.Time]
+	if err := tr.Scan(`["2020-01-01 10:00:00+00","2020-01-02 10:00:00.5+02")`); err != nil {
+		t.Fatalf("Failed to Scan() tstzrange: %s", err)
+	}
+	if !tr.Lower.Equal(time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)) ||
+		!tr.Upper.Equal(time.Date(2020, 1, 2, 8, 0, 0, 500000000, time.UTC)) {
+		t.Errorf("Unexpected tstzrange bounds %s, %s", tr.Lower, tr.Upper)
+	}
+
+	// time.Time has no infinity, so infinite bounds are unbounded
+	if err := tr.Scan(`[-infinity,"2020-01-01 10:00:00")`); err != nil {
+		t.Fatalf("Failed to Scan() infinite bound: %s", err)
+	}
+	if tr.LowerType != libpq.Unbounded || tr.UpperType != libpq.Exclusive {
+		t.Errorf("Unexpected infinite bound types %d, %d", tr.LowerType, tr.UpperType)
+	}
+	if err := tr.Scan(`["2020-01-01",infinity]`); err != nil || tr.UpperType != libpq.Unbounded {
+		t.Errorf("Unexpected infinite upper bound %+v (err=%v)", tr, err)
+	}
+
+	for _, text := range []string{"", "[1,5", "1,5", "[1;5)", "[1,5) x", "[a,5)"} {
+		var r libpq.Range[int32]
+		if err := r.Scan(text); err == nil {
+			t.Errorf("Expected error scanning %q", text)
+		}
+	}
+}
+
+func TestMultirangeScan(t *testing.T) {
+	var m libpq.Multirange[int64]
+	if err := m.Scan("{[1,3), [5,7)}"); err != nil {
+		t.Fatalf("Failed to Scan() multirange: %s", err)
+	}
+	if len(m) != 2 || m[0].Lower != 1 || m[1].Upper != 7 || m[1].UpperType != libpq.Exclusive {
+		t.Errorf("Unexpected multirange %+v", m)
+	}
+	if err := m.Scan("{}"); err != nil || m == nil || len(m) != 0 {
+		t.Errorf("Unexpected empty multirange %+v: %v", m, err)
+	}
+}
+
+func TestRange(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	for _, test := range int4RangeTests {
+		var r libpq.Range[int32]
+		if err := db.QueryRow("select $1::int4range", test.expect).Scan(&r); err != nil {
+			t.Fatalf("Failed to Scan() int4range: %s", err)
+		}
+		// the server canonicalizes discrete ranges to [)
+		var same bool
+		err := db.QueryRow("select $1::int4range = $2::int4range", r, test.expect).Scan(&same)
+		if err != nil || !same {
+			t.Errorf("%+v came back as %+v: %v", test.expect, r, err)
+		}
+	}
+
+	var n libpq.Range[string]
+	if err := db.QueryRow("select numrange(1.5, 2.25, '[]')").Scan(&n); err != nil {
+		t.Fatalf("Failed to Scan() numrange: %s", err)
+	}
+	if n != (libpq.Range[string]{Lower: "1.5", Upper: "2.25"}) {
+		t.Errorf("Unexpected numrange %+v", n)
+	}
+
+	start := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	ts := libpq.Range[time.Time]{Lower: start, Upper: start.Add(time.Hour), UpperType: libpq.Exclusive}
+	for _, typ := range []string{"tsrange", "tstzrange"} {
+		var got libpq.Range[time.Time]
+		if err := db.QueryRow("select $1::"+typ, ts).Scan(&got); err != nil {
+			t.Fatalf("Failed to Scan() %s: %s", typ, err)
+		}
+		if !got.Lower.Equal(ts.Lower) || !got.Upper.Equal(ts.Upper) || got.UpperType != libpq.Exclusive {
+			t.Errorf("Unexpected %s %+v", typ, got)
+		}
+	}
+
+	var d libpq.Range[time.Time]
+	if err := db.QueryRow("select daterange('2020-01-01', '2020-02-01')").Scan(&d); err != nil {
+		t.Fatalf("Failed to Scan() daterange: %s", err)
+	}
+	if !d.Lower.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) || d.UpperType != libpq.Exclusive {
+		t.Errorf("Unexpected daterange %+v", d)
+	}
+
+	for _, typ := range []string{"tsrange", "tstzrange", "daterange"} {
+		var got libpq.Range[time.Time]
+		err := db.QueryRow("select " + typ + "('-infinity', 'infinity')").Scan(&got)
+		if err != nil || got.LowerType != libpq.Unbounded || got.UpperType != libpq.Unbounded {
+			t.Errorf("Unexpected infinite %s %+v (err=%v)", typ, got, err)
+		}
+	}
+
+	var version int
+	if err := db.QueryRow("select current_setting('server_version_num')::int").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version < 140000 {
+		t.Log("Skipping multiranges, which need Postgres 14")
+		return
+	}
+	mr := libpq.Multirange[int64]{
+		{Lower: 1, Upper: 3, UpperType: libpq.Exclusive},
+		{Lower: 5, Upper: 7, UpperType: libpq.Exclusive},
+	}
+	var got libpq.Multirange[int64]
+	if err := db.QueryRow("select $1::int8multirange", mr).Scan(&got); err != nil {
+		t.Fatalf("Failed to Scan() int8multirange: %s", err)
+	}
+	if len(got) != 2 || got[0] != mr[0] || got[1] != mr[1] {
+		t.Errorf("Unexpected int8multirange %+v", got)
+	}
+}
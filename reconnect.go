@@ -0,0 +1,217 @@
+package libpq
+
+/*
+#include <poll.h>
+#include <stdlib.h>
+#include <libpq-fe.h>
+
+// Drive PQresetStart/PQresetPoll to completion, waiting at most timeout
+// seconds for the socket each time libpq asks us to. Returns 1 on success.
+static int resetConn(PGconn *conn, int timeout) {
+	PostgresPollingStatusType status = PGRES_POLLING_WRITING;
+	struct pollfd pfd;
+	int n;
+
+	if (!PQresetStart(conn)) {
+		return 0;
+	}
+
+	while (1) {
+		pfd.fd = PQsocket(conn);
+		if (pfd.fd < 0) {
+			return 0;
+		}
+
+		if (status == PGRES_POLLING_READING) {
+			pfd.events = POLLIN;
+		} else {
+			pfd.events = POLLOUT;
+		}
+		n = poll(&pfd, 1, timeout * 1000);
+		if (n <= 0) {
+			return 0;
+		}
+
+		status = PQresetPoll(conn);
+		if (status == PGRES_POLLING_OK) {
+			return 1;
+		}
+		if (status == PGRES_POLLING_FAILED) {
+			return 0;
+		}
+	}
+}
+*/
+import "C"
+import (
+	"database/sql/driver"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultReconnectAttempts = 5
+	defaultInitialBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff        = 30 * time.Second
+	defaultResetTimeout      = 10 * time.Second
+)
+
+// ReconnectPolicy controls automatic reconnection of connections whose
+// server connection has been lost. A connection that is reset is
+// reinitialized before it is used again: the Connector's SessionInit
+// statements are rerun, channels it was LISTENing on are listened to again,
+// and its cached prepared statements are prepared again.
+//
+// Connections are never reset in the middle of a transaction; the
+// transaction fails and database/sql discards the connection instead.
+type ReconnectPolicy struct {
+	// Maximum number of reset attempts before giving up (default 5).
+	MaxAttempts int
+
+	// Delay before the second attempt (default 100ms). The delay doubles
+	// after each further attempt, up to MaxBackoff (default 30s), and a
+	// random jitter of up to half the delay is subtracted.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Maximum time to wait for the server during a single attempt
+	// (default 10s).
+	ResetTimeout time.Duration
+
+	// If non-nil, OnReconnect is called after every reset attempt.
+	OnReconnect func(ReconnectEvent)
+}
+
+// ReconnectEvent describes a single reset attempt.
+type ReconnectEvent struct {
+	// Attempt number, starting at 1.
+	Attempt int
+
+	// nil if the connection was successfully reset and reinitialized.
+	Err error
+
+	// true if this was the last attempt and the connection will be
+	// discarded.
+	GaveUp bool
+}
+
+func (p *ReconnectPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return defaultReconnectAttempts
+	}
+	return p.MaxAttempts
+}
+
+func (p *ReconnectPolicy) resetTimeout() C.int {
+	timeout := p.ResetTimeout
+	if timeout <= 0 {
+		timeout = defaultResetTimeout
+	}
+	return C.int((timeout + time.Second - 1) / time.Second)
+}
+
+// backoff returns how long to wait after the given (1-based) failed attempt.
+func (p *ReconnectPolicy) backoff(attempt int) time.Duration {
+	d, max := p.InitialBackoff, p.MaxBackoff
+	if d <= 0 {
+		d = defaultInitialBackoff
+	}
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d - time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// ensureConn is called before sending a new command. If the server
+// connection has been lost, it is reset when a ReconnectPolicy is configured;
+// otherwise driver.ErrBadConn is returned so database/sql discards it.
+func (c *libpqConn) ensureConn() error {
+	if c.broken {
+		return driver.ErrBadConn
+	}
+	if c.reconnect != nil {
+		// pick up a connection closed by the server while we were idle
+		C.PQconsumeInput(c.db)
+	}
+	if C.PQstatus(c.db) == C.CONNECTION_OK {
+		return nil
+	}
+	if c.reconnect == nil || c.inTx || c.resetting {
+		return driver.ErrBadConn
+	}
+	return c.reset()
+}
+
+// reset reestablishes the server connection according to c.reconnect and
+// restores the session state that was lost with it.
+func (c *libpqConn) reset() error {
+	p := c.reconnect
+	c.resetting = true
+	defer func() { c.resetting = false }()
+
+	attempts := p.maxAttempts()
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(p.backoff(attempt - 1))
+		}
+
+		var err error
+		if C.resetConn(c.db, p.resetTimeout()) == 1 {
+			// we may have reached a different server
+			c.money = nil
+			if err = c.loadTypes(); err == nil {
+				err = c.initSession()
+			}
+		} else {
+			err = errors.New("libpq: reconnect failed: " + C.GoString(C.PQerrorMessage(c.db)))
+		}
+
+		if p.OnReconnect != nil {
+			p.OnReconnect(ReconnectEvent{
+				Attempt: attempt,
+				Err:     err,
+				GaveUp:  err != nil && attempt == attempts,
+			})
+		}
+		if err == nil {
+			if c.connector != nil {
+				c.connector.noteConnected(c)
+			}
+			return nil
+		}
+	}
+	return driver.ErrBadConn
+}
+
+// initSession runs the session initialization statements on a freshly
+// (re)established connection and restores listens and prepared statements.
+func (c *libpqConn) initSession() error {
+	for _, query := range c.sessionInit {
+		if _, err := c.exec(query, false); err != nil {
+			return err
+		}
+	}
+
+	for _, query := range c.listens {
+		if _, err := c.exec(query, false); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range c.stmtCache {
+		if stmt, ok := v.(*libpqStmt); ok {
+			if _, err := c.prepare(stmt.name, stmt.query); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
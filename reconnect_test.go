@@ -0,0 +1,112 @@
+package libpq_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+// kill the server connection backing db, which must hold exactly one connection
+func killBackend(t *testing.T, db *sql.DB) {
+	var pid int64
+	if err := db.QueryRow("select pg_backend_pid()").Scan(&pid); err != nil {
+		t.Fatalf("Failed to get backend pid: %s", err)
+	}
+
+	other := getConn(t)
+	defer other.Close()
+	if _, err := other.Exec("select pg_terminate_backend($1)", pid); err != nil {
+		t.Fatalf("Failed to terminate backend %d: %s", pid, err)
+	}
+
+	// give the server a moment to actually close the connection
+	time.Sleep(100 * time.Millisecond)
+}
+
+func TestReconnect(t *testing.T) {
+	var events []libpq.ReconnectEvent
+	c := libpq.NewConnector(testDSN())
+	c.SessionInit = []string{"SET application_name = 'reconnect_test'"}
+	c.Reconnect = &libpq.ReconnectPolicy{
+		OnReconnect: func(ev libpq.ReconnectEvent) { events = append(events, ev) },
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	stmt, err := db.Prepare("select $1::int + 1")
+	if err != nil {
+		t.Fatalf("Failed to prepare statement: %s", err)
+	}
+	defer stmt.Close()
+
+	killBackend(t, db)
+
+	// session initialization should have been rerun
+	var name string
+	if err = db.QueryRow("select current_setting('application_name')").Scan(&name); err != nil {
+		t.Fatalf("Query after reconnect failed: %s", err)
+	}
+	if name != "reconnect_test" {
+		t.Errorf("Unexpected application_name '%s' after reconnect", name)
+	}
+	if len(events) != 1 || events[0].Err != nil {
+		t.Fatalf("Expected one successful reconnect, got %v", events)
+	}
+
+	// ... and the prepared statement should have been re-prepared
+	var val int64
+	if err = stmt.QueryRow(41).Scan(&val); err != nil || val != 42 {
+		t.Fatalf("Prepared statement failed after reconnect: %v (got %d)", err, val)
+	}
+}
+
+func TestReconnectListen(t *testing.T) {
+	c := libpq.NewConnector(testDSN())
+	c.Reconnect = &libpq.ReconnectPolicy{}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var pid int64
+	if err := db.QueryRow("select pg_backend_pid()").Scan(&pid); err != nil {
+		t.Fatalf("Failed to get backend pid: %s", err)
+	}
+
+	notes, err := db.Query("LISTEN reconnect_channel")
+	if err != nil {
+		t.Fatalf("Failed to prepare LISTEN: %s", err)
+	}
+	defer notes.Close()
+
+	other := getConn(t)
+	defer other.Close()
+	if _, err = other.Exec("select pg_terminate_backend($1)", pid); err != nil {
+		t.Fatalf("Failed to terminate backend %d: %s", pid, err)
+	}
+
+	// keep notifying until the listener has reconnected and re-LISTENed
+	done := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(100 * time.Millisecond):
+				other.Exec("NOTIFY reconnect_channel, 'after reset'")
+			}
+		}
+	}()
+	defer close(done)
+
+	var payload string
+	if !notes.Next() {
+		t.Fatalf("Did not receive NOTIFY after reconnect: %s", notes.Err())
+	}
+	mustScan(t, notes, &payload)
+	if payload != "after reset" {
+		t.Fatalf("Received unexpected payload '%s' (expected 'after reset')", payload)
+	}
+}
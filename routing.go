@@ -0,0 +1,342 @@
+package libpq
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+)
+
+// RoutingConnector is a driver.Connector spreading work across a primary
+// server and its streaming replicas. Every connection it returns is backed by
+// a connection to the primary and, once the first read-only transaction is
+// started on it, a connection to one of the standbys (picked round-robin).
+// Read-only transactions (sql.TxOptions{ReadOnly: true}) run on the standby;
+// everything else runs on the primary.
+//
+// A standby that cannot be reached, or that turns out to have been promoted,
+// is skipped in favor of the next one, falling back to the primary when none
+// is usable. OnFailover is called whenever that happens.
+type RoutingConnector struct {
+	Primary  *Connector
+	Standbys []*Connector
+
+	// If non-nil, called when a read-only transaction could not be routed to
+	// the standby it would normally have used.
+	OnFailover func(FailoverEvent)
+
+	next uint32
+}
+
+// NewRoutingConnector returns a RoutingConnector sending writes to primary
+// and read-only transactions to standbys.
+func NewRoutingConnector(primary *Connector, standbys ...*Connector) *RoutingConnector {
+	return &RoutingConnector{Primary: primary, Standbys: standbys}
+}
+
+// Implement Connector interface.
+func (rc *RoutingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	primary, err := rc.Primary.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &routedConn{rc: rc, primary: primary.(*libpqConn)}, nil
+}
+
+// Implement Connector interface.
+func (rc *RoutingConnector) Driver() driver.Driver {
+	return pqDriver
+}
+
+// connectStandby returns a connection to the next usable standby, or nil if
+// there is none, along with the standbys that had to be skipped.
+func (rc *RoutingConnector) connectStandby(ctx context.Context) (*libpqConn, []*Connector) {
+	n := len(rc.Standbys)
+	if n == 0 {
+		return nil, nil
+	}
+
+	var skipped []*Connector
+	start := int(atomic.AddUint32(&rc.next, 1) % uint32(n))
+	for i := 0; i < n; i++ {
+		sc := rc.Standbys[(start+i)%n]
+		dc, err := sc.Connect(ctx)
+		if err == nil {
+			conn := dc.(*libpqConn)
+			if role, err := conn.ServerRole(); err == nil && role == RoleStandby {
+				return conn, skipped
+			}
+			conn.Close()
+		}
+		skipped = append(skipped, sc)
+	}
+	return nil, skipped
+}
+
+// failover reports that read-only work meant for the skipped standbys is
+// going to conn instead.
+func (rc *RoutingConnector) failover(skipped []*Connector, conn *libpqConn) {
+	if rc.OnFailover == nil || len(skipped) == 0 {
+		return
+	}
+	role, _ := conn.ServerRole()
+	for _, sc := range skipped {
+		sc.mu.Lock()
+		prevHost, prevPort := sc.host, sc.port
+		sc.mu.Unlock()
+		rc.OnFailover(FailoverEvent{
+			PrevHost: prevHost,
+			PrevPort: prevPort,
+			Host:     conn.Host(),
+			Port:     conn.Port(),
+			Role:     role,
+		})
+	}
+}
+
+// routedConn sends each transaction to the primary or a standby connection.
+// database/sql never uses a connection concurrently, so no locking is needed.
+type routedConn struct {
+	rc      *RoutingConnector
+	primary *libpqConn
+	standby *libpqConn
+
+	// connection the current transaction runs on, if any
+	cur *libpqConn
+}
+
+var _ Conn = (*routedConn)(nil)
+
+func (c *routedConn) target() *libpqConn {
+	if c.cur != nil {
+		return c.cur
+	}
+	return c.primary
+}
+
+func (c *routedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// Implement NamedValueChecker interface.
+func (c *routedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return c.target().CheckNamedValue(nv)
+}
+
+// Implement Execer interface.
+func (c *routedConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	return c.target().Exec(query, args)
+}
+
+// Implement ConnPrepareContext interface.
+func (c *routedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	conn := c.target()
+	stmt, err := conn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	s := &routedStmt{c: c, query: query, nparams: stmt.NumInput()}
+	s.stmts = map[*libpqConn]*libpqStmt{conn: stmt.(*libpqStmt)}
+	return s, nil
+}
+
+// Implement ExecerContext interface.
+func (c *routedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.target().ExecContext(ctx, query, args)
+}
+
+// Implement QueryerContext interface.
+func (c *routedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.target().QueryContext(ctx, query, args)
+}
+
+func (c *routedConn) Close() error {
+	err := c.primary.Close()
+	if c.standby != nil {
+		if serr := c.standby.Close(); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// Implement Validator interface. A broken standby is dropped, and another
+// picked on the next read-only transaction.
+func (c *routedConn) IsValid() bool {
+	if c.standby != nil && !c.standby.IsValid() {
+		c.standby.Close()
+		c.standby = nil
+	}
+	return c.primary.IsValid()
+}
+
+func (c *routedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// Implement ConnBeginTx interface.
+func (c *routedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.cur != nil {
+		return nil, errors.New("libpq: transaction already in progress")
+	}
+
+	conn := c.primary
+	if opts.ReadOnly {
+		if c.standby == nil {
+			var skipped []*Connector
+			c.standby, skipped = c.rc.connectStandby(ctx)
+			if c.standby != nil {
+				c.rc.failover(skipped, c.standby)
+			} else {
+				c.rc.failover(skipped, c.primary)
+			}
+		}
+		if c.standby != nil {
+			conn = c.standby
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		if conn == c.standby && err == driver.ErrBadConn {
+			// pick a different standby next time
+			c.standby.Close()
+			c.standby = nil
+		}
+		return nil, err
+	}
+	c.cur = conn
+	return &routedTx{c: c, tx: tx}, nil
+}
+
+func (c *routedConn) Host() string {
+	return c.target().Host()
+}
+
+func (c *routedConn) Port() string {
+	return c.target().Port()
+}
+
+func (c *routedConn) ServerRole() (ServerRole, error) {
+	return c.target().ServerRole()
+}
+
+func (c *routedConn) ParameterStatus(name string) (string, bool) {
+	return c.target().ParameterStatus(name)
+}
+
+func (c *routedConn) ServerVersion() int {
+	return c.target().ServerVersion()
+}
+
+func (c *routedConn) ProtocolVersion() int {
+	return c.target().ProtocolVersion()
+}
+
+func (c *routedConn) BackendPID() int {
+	return c.target().BackendPID()
+}
+
+func (c *routedConn) SSLInUse() bool {
+	return c.target().SSLInUse()
+}
+
+func (c *routedConn) SendBatch(b *Batch) ([]BatchResult, error) {
+	return c.target().SendBatch(b)
+}
+
+type routedTx struct {
+	c  *routedConn
+	tx driver.Tx
+}
+
+func (tx *routedTx) Commit() error {
+	tx.c.cur = nil
+	return tx.tx.Commit()
+}
+
+func (tx *routedTx) Rollback() error {
+	tx.c.cur = nil
+	return tx.tx.Rollback()
+}
+
+func (tx *routedTx) Savepoint(name string) error {
+	return tx.tx.(Tx).Savepoint(name)
+}
+
+func (tx *routedTx) RollbackTo(name string) error {
+	return tx.tx.(Tx).RollbackTo(name)
+}
+
+func (tx *routedTx) Release(name string) error {
+	return tx.tx.(Tx).Release(name)
+}
+
+// routedStmt is a statement prepared on a routedConn. database/sql uses it
+// inside the connection's transactions too (through Tx.Stmt), so it runs on
+// whichever connection is current, being prepared there as needed.
+type routedStmt struct {
+	c       *routedConn
+	query   string
+	nparams int
+	stmts   map[*libpqConn]*libpqStmt
+}
+
+// target returns the statement prepared on the connection s should run on.
+func (s *routedStmt) target() (*libpqStmt, error) {
+	conn := s.c.target()
+	if stmt, ok := s.stmts[conn]; ok {
+		return stmt, nil
+	}
+	stmt, err := conn.Prepare(s.query)
+	if err != nil {
+		return nil, err
+	}
+	s.stmts[conn] = stmt.(*libpqStmt)
+	return s.stmts[conn], nil
+}
+
+func (s *routedStmt) Close() error {
+	for _, stmt := range s.stmts {
+		stmt.Close()
+	}
+	return nil
+}
+
+func (s *routedStmt) NumInput() int {
+	return s.nparams
+}
+
+func (s *routedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	stmt, err := s.target()
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Exec(args)
+}
+
+func (s *routedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	stmt, err := s.target()
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Query(args)
+}
+
+// Implement StmtExecContext interface.
+func (s *routedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	stmt, err := s.target()
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args)
+}
+
+// Implement StmtQueryContext interface.
+func (s *routedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	stmt, err := s.target()
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args)
+}
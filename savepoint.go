@@ -0,0 +1,121 @@
+package libpq
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Tx is implemented by the driver transactions this package returns, adding
+// savepoints for nesting work inside a transaction.
+type Tx interface {
+	driver.Tx
+
+	// Savepoint establishes a savepoint called name, which RollbackTo undoes
+	// the transaction's work back to and Release forgets (keeping the work).
+	Savepoint(name string) error
+	RollbackTo(name string) error
+	Release(name string) error
+}
+
+var _ Tx = (*libpqTx)(nil)
+
+func (tx *libpqTx) Savepoint(name string) error {
+	_, err := tx.c.exec("SAVEPOINT "+QuoteIdentifier(name), false)
+	return err
+}
+
+func (tx *libpqTx) RollbackTo(name string) error {
+	_, err := tx.c.exec("ROLLBACK TO SAVEPOINT "+QuoteIdentifier(name), false)
+	return err
+}
+
+func (tx *libpqTx) Release(name string) error {
+	_, err := tx.c.exec("RELEASE SAVEPOINT "+QuoteIdentifier(name), false)
+	return err
+}
+
+// QuoteIdentifier quotes name for use as an identifier (e.g., a table, column
+// or savepoint name) in a query. Identifiers can't contain NUL bytes, so name
+// is cut short at the first one.
+func QuoteIdentifier(name string) string {
+	if end := strings.IndexByte(name, 0); end >= 0 {
+		name = name[:end]
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Transactor is a *sql.DB, *sql.Conn or *sql.Tx, for Transact.
+type Transactor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// savepoints numbers the savepoints made by Transact.
+var savepoints uint64
+
+// Transact runs fn in a transaction on db, committing it if fn returns nil
+// and rolling it back if fn returns an error or panics. If db is already a
+// transaction, fn runs in a savepoint in it instead, which is released or
+// rolled back to, so that functions using Transact compose:
+//
+//	err := libpq.Transact(ctx, db, func(tx *sql.Tx) error {
+//		if _, err := tx.ExecContext(ctx, "INSERT INTO orders ..."); err != nil {
+//			return err
+//		}
+//		// reserveStock may use Transact with tx, getting a savepoint
+//		return reserveStock(ctx, tx, items)
+//	})
+func Transact(ctx context.Context, db Transactor, fn func(tx *sql.Tx) error) error {
+	if tx, ok := db.(*sql.Tx); ok {
+		return savepoint(ctx, tx, fn)
+	}
+
+	beginner, ok := db.(interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	})
+	if !ok {
+		return fmt.Errorf("libpq: cannot begin a transaction on %T", db)
+	}
+	tx, err := beginner.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func savepoint(ctx context.Context, tx *sql.Tx, fn func(tx *sql.Tx) error) error {
+	name := QuoteIdentifier(fmt.Sprintf("libpq_savepoint_%d", atomic.AddUint64(&savepoints, 1)))
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		// rolling back to a savepoint keeps it, so release it too
+		if _, rerr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name+"; RELEASE SAVEPOINT "+name); rerr != nil {
+			return fmt.Errorf("%w (and rolling back to the savepoint failed: %s)", err, rerr)
+		}
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
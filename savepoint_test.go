@@ -0,0 +1,123 @@
+package libpq_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"name":       `"name"`,
+		`with "quo"`: `"with ""quo"""`,
+		"nul\x00cut": `"nul"`,
+		"":           `""`,
+	}
+	for in, expect := range tests {
+		if got := libpq.QuoteIdentifier(in); got != expect {
+			t.Errorf("QuoteIdentifier(%q) = %s, expected %s", in, got, expect)
+		}
+	}
+}
+
+func TestSavepoint(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+	mustExec(t, db, "DROP TABLE IF EXISTS test")
+	mustExec(t, db, "CREATE TABLE test (a int)")
+	defer db.Exec("DROP TABLE test")
+
+	dc, err := db.Driver().Open(testDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+	dtx, err := dc.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := dtx.(libpq.Tx)
+	exec := func(query string) {
+		if _, err := dc.(driver.Execer).Exec(query, nil); err != nil {
+			t.Fatalf("%s failed: %s", query, err)
+		}
+	}
+	exec("insert into test values (1)")
+	if err := tx.Savepoint(`odd "name"`); err != nil {
+		t.Fatal(err)
+	}
+	exec("insert into test values (2)")
+	if err := tx.RollbackTo(`odd "name"`); err != nil {
+		t.Fatal(err)
+	}
+	exec("insert into test values (3)")
+	if err := tx.Release(`odd "name"`); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var sum int
+	if err := db.QueryRow("select sum(a) from test").Scan(&sum); err != nil || sum != 4 {
+		t.Errorf("Expected sum 4, got %d (err=%v)", sum, err)
+	}
+}
+
+func TestTransact(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+	mustExec(t, db, "DROP TABLE IF EXISTS test")
+	mustExec(t, db, "CREATE TABLE test (a int primary key)")
+	defer db.Exec("DROP TABLE test")
+
+	ctx := context.Background()
+	insert := func(a int) func(*sql.Tx) error {
+		return func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "insert into test values ($1)", a)
+			return err
+		}
+	}
+	errFailed := errors.New("failed")
+
+	err := libpq.Transact(ctx, db, func(tx *sql.Tx) error {
+		if err := libpq.Transact(ctx, tx, insert(1)); err != nil {
+			return err
+		}
+		// a duplicate key error only undoes the nested call
+		if err := libpq.Transact(ctx, tx, insert(1)); err == nil {
+			t.Error("Expected duplicate key error")
+		}
+		return libpq.Transact(ctx, tx, insert(2))
+	})
+	if err != nil {
+		t.Fatalf("Transact failed: %s", err)
+	}
+
+	err = libpq.Transact(ctx, db, func(tx *sql.Tx) error {
+		if err := libpq.Transact(ctx, tx, insert(3)); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if err != errFailed {
+		t.Errorf("Expected %v, got %v", errFailed, err)
+	}
+
+	func() {
+		defer func() { recover() }()
+		libpq.Transact(ctx, db, func(tx *sql.Tx) error {
+			insert(4)(tx)
+			panic("boom")
+		})
+	}()
+
+	var sum int
+	if err := db.QueryRow("select sum(a) from test").Scan(&sum); err != nil || sum != 3 {
+		t.Errorf("Expected only 1 and 2 to be committed, got sum %d (err=%v)", sum, err)
+	}
+}
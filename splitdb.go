@@ -0,0 +1,201 @@
+package libpq
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultCheckInterval = 5 * time.Second
+
+// SplitOptions configures a SplitDB.
+type SplitOptions struct {
+	// Replicas whose replay lag exceeds MaxLag are not used until they catch
+	// up. Zero means any lag is acceptable.
+	MaxLag time.Duration
+
+	// How often replicas are checked (default 5s).
+	CheckInterval time.Duration
+}
+
+// SplitDB is a read/write splitting wrapper around one primary and any
+// number of streaming replicas, each opened with sql.OpenDB from a Connector.
+// Query, QueryRow and read-only transactions are sent to the replicas in
+// round-robin order; everything else goes to the primary.
+//
+// Replicas are health-checked in the background. A replica that cannot be
+// reached, is no longer in recovery, or lags behind the primary by more than
+// MaxLag is skipped; when no replica is usable, reads go to the primary. With
+// a MaxLag, a replica must also be streaming from the primary, since one
+// whose WAL receiver has stopped can't tell how far behind it is.
+type SplitDB struct {
+	primary  *sql.DB
+	replicas []*replica
+	opts     SplitOptions
+	next     uint32
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+type replica struct {
+	db      *sql.DB
+	healthy int32 // accessed atomically
+}
+
+// NewSplitDB opens a SplitDB and starts health-checking its replicas. Until
+// the first check of a replica completes, it is not used.
+func NewSplitDB(opts SplitOptions, primary *Connector, replicas ...*Connector) *SplitDB {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = defaultCheckInterval
+	}
+
+	s := &SplitDB{
+		primary: sql.OpenDB(primary),
+		opts:    opts,
+		stop:    make(chan struct{}),
+	}
+	for _, rc := range replicas {
+		s.replicas = append(s.replicas, &replica{db: sql.OpenDB(rc)})
+	}
+
+	if len(s.replicas) > 0 {
+		s.wg.Add(1)
+		go s.healthCheck()
+	}
+	return s
+}
+
+// Primary returns the primary's database handle.
+func (s *SplitDB) Primary() *sql.DB {
+	return s.primary
+}
+
+// Reader returns the database handle reads are currently sent to: the next
+// healthy replica, or the primary if there is none.
+func (s *SplitDB) Reader() *sql.DB {
+	n := len(s.replicas)
+	if n == 0 {
+		return s.primary
+	}
+
+	start := int(atomic.AddUint32(&s.next, 1) % uint32(n))
+	for i := 0; i < n; i++ {
+		r := s.replicas[(start+i)%n]
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			return r.db
+		}
+	}
+	return s.primary
+}
+
+func (s *SplitDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.Reader().Query(query, args...)
+}
+
+func (s *SplitDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.Reader().QueryContext(ctx, query, args...)
+}
+
+func (s *SplitDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return s.Reader().QueryRow(query, args...)
+}
+
+func (s *SplitDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.Reader().QueryRowContext(ctx, query, args...)
+}
+
+func (s *SplitDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.primary.Exec(query, args...)
+}
+
+func (s *SplitDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.primary.ExecContext(ctx, query, args...)
+}
+
+func (s *SplitDB) Prepare(query string) (*sql.Stmt, error) {
+	return s.primary.Prepare(query)
+}
+
+func (s *SplitDB) Begin() (*sql.Tx, error) {
+	return s.primary.Begin()
+}
+
+// BeginTx starts a transaction on a replica if opts.ReadOnly is set, and on
+// the primary otherwise.
+func (s *SplitDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if opts != nil && opts.ReadOnly {
+		return s.Reader().BeginTx(ctx, opts)
+	}
+	return s.primary.BeginTx(ctx, opts)
+}
+
+// Close stops health checking and closes the primary and all replicas. It is
+// safe to call more than once.
+func (s *SplitDB) Close() error {
+	s.closeOnce.Do(func() { close(s.stop) })
+	s.wg.Wait()
+
+	err := s.primary.Close()
+	for _, r := range s.replicas {
+		if rerr := r.db.Close(); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+func (s *SplitDB) healthCheck() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.opts.CheckInterval)
+	defer ticker.Stop()
+	for {
+		for _, r := range s.replicas {
+			healthy := int32(0)
+			if s.checkReplica(r) {
+				healthy = 1
+			}
+			atomic.StoreInt32(&r.healthy, healthy)
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// A replica whose replay position has caught up with what it received is
+// considered current even if the last replayed transaction is old, as long
+// as it is still receiving: with no WAL receiver, nothing more arrives and
+// the positions match however stale the replica is. pg_stat_wal_receiver
+// only has a row while the receiver runs (its other columns may be hidden
+// from unprivileged users).
+const replicaLagQuery = `SELECT pg_is_in_recovery(),
+	EXISTS (SELECT 1 FROM pg_stat_wal_receiver),
+	CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`
+
+func (s *SplitDB) checkReplica(r *replica) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.CheckInterval)
+	defer cancel()
+
+	var inRecovery, streaming bool
+	var lag float64
+	if err := r.db.QueryRowContext(ctx, replicaLagQuery).Scan(&inRecovery, &streaming, &lag); err != nil {
+		return false
+	}
+	if !inRecovery {
+		return false
+	}
+	if s.opts.MaxLag <= 0 {
+		return true
+	}
+	return streaming && time.Duration(lag*float64(time.Second)) <= s.opts.MaxLag
+}
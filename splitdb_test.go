@@ -0,0 +1,45 @@
+package libpq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func TestSplitDB(t *testing.T) {
+	// the "replica" is really the primary, so it never passes its health
+	// check and reads must go to the primary
+	s := libpq.NewSplitDB(libpq.SplitOptions{MaxLag: time.Second, CheckInterval: 50 * time.Millisecond},
+		libpq.NewConnector(testDSN()), libpq.NewConnector(testDSN()))
+	defer s.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	if s.Reader() != s.Primary() {
+		t.Fatalf("Expected reads to fall back to the primary")
+	}
+
+	var one int64
+	if err := s.QueryRow("select 1").Scan(&one); err != nil || one != 1 {
+		t.Fatalf("Query through SplitDB failed: %v", err)
+	}
+
+	tx, err := s.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to begin read-only transaction: %s", err)
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("create temp table test (i int)"); err == nil {
+		t.Fatalf("Expected CREATE TABLE to fail in a read-only transaction")
+	}
+	tx.Rollback()
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close SplitDB: %s", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Second Close() failed: %s", err)
+	}
+}
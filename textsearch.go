@@ -0,0 +1,262 @@
+package libpq
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// TSVector is the value of a tsvector column, its lexemes in order.
+type TSVector []Lexeme
+
+// Lexeme is a normalized word in a TSVector, with the positions it occurs at
+// (if the vector has any).
+type Lexeme struct {
+	Word      string
+	Positions []LexemePosition
+}
+
+// LexemePosition is a position of a Lexeme, 1 to 16383, and its weight, one
+// of 'A', 'B', 'C' or 'D' (the default).
+type LexemePosition struct {
+	Pos    int
+	Weight byte
+}
+
+// TSQuery is the value of a tsquery column, as the sequence of its items.
+type TSQuery []TSQueryItem
+
+// TSQueryItem is a lexeme or operator of a TSQuery.
+type TSQueryItem struct {
+	// One of "&", "|", "!", "(", ")" or a phrase operator ("<->", "<2>",
+	// etc.), or "" for a lexeme.
+	Operator string
+
+	// For lexemes, the lexeme itself, the weights it is restricted to (e.g.
+	// "AB", or "" for any), and whether it is a prefix (written with :*).
+	Lexeme  string
+	Weights string
+	Prefix  bool
+}
+
+var errTextSearchFormat = errors.New("libpq: invalid text search format")
+
+// scanLexeme reads a lexeme, quoted or not, from the start of s. Quotes
+// are doubled or backslash-escaped within quoted lexemes.
+func scanLexeme(s, stops string) (lexeme, rest string, err error) {
+	if !strings.HasPrefix(s, "'") {
+		end := strings.IndexAny(s, stops)
+		if end < 0 {
+			end = len(s)
+		}
+		if end == 0 {
+			return "", s, errTextSearchFormat
+		}
+		return s[:end], s[end:], nil
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+		case s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == '\'':
+			return b.String(), s[i+1:], nil
+		}
+		b.WriteByte(s[i])
+	}
+	return "", s, errTextSearchFormat
+}
+
+func writeLexeme(b *strings.Builder, lexeme string) {
+	b.WriteByte('\'')
+	for i := 0; i < len(lexeme); i++ {
+		switch lexeme[i] {
+		case '\'':
+			b.WriteByte('\'')
+		case '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(lexeme[i])
+	}
+	b.WriteByte('\'')
+}
+
+// ParseTSVector parses the text representation of a tsvector, e.g.
+// "'cat':3 'fat':2,4B".
+func ParseTSVector(s string) (TSVector, error) {
+	v := TSVector{}
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return v, nil
+		}
+		var lex Lexeme
+		var err error
+		if lex.Word, s, err = scanLexeme(s, " :"); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(s, ":") {
+			// comma-separated positions, each with an optional weight
+			for s = s[1:]; ; s = s[1:] {
+				end := 0
+				for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+					end++
+				}
+				pos, err := strconv.Atoi(s[:end])
+				if err != nil {
+					return nil, errTextSearchFormat
+				}
+				p := LexemePosition{Pos: pos, Weight: 'D'}
+				if s = s[end:]; s != "" && strings.IndexByte("ABCDabcd", s[0]) >= 0 {
+					p.Weight, s = s[0]&^0x20, s[1:]
+				}
+				lex.Positions = append(lex.Positions, p)
+				if !strings.HasPrefix(s, ",") {
+					break
+				}
+			}
+		}
+		if s != "" && s[0] != ' ' {
+			return nil, errTextSearchFormat
+		}
+		v = append(v, lex)
+	}
+}
+
+// String returns v in the format Postgres uses.
+func (v TSVector) String() string {
+	var b strings.Builder
+	for i, lex := range v {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		writeLexeme(&b, lex.Word)
+		for j, p := range lex.Positions {
+			if j == 0 {
+				b.WriteByte(':')
+			} else {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Itoa(p.Pos))
+			if p.Weight != 'D' && p.Weight != 0 {
+				b.WriteByte(p.Weight)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Implement Valuer interface.
+func (v TSVector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return v.String(), nil
+}
+
+// Implement Scanner interface.
+func (v *TSVector) Scan(src interface{}) error {
+	s, null, err := srcText(src, "TSVector")
+	if err != nil || null {
+		*v = nil
+		return err
+	}
+	tv, err := ParseTSVector(s)
+	if err != nil {
+		return err
+	}
+	*v = tv
+	return nil
+}
+
+// ParseTSQuery parses the text representation of a tsquery, e.g.
+// "'fat' & ( 'rat':AB | 'cat':* ) <-> !'mouse'".
+func ParseTSQuery(s string) (TSQuery, error) {
+	q := TSQuery{}
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return q, nil
+		}
+
+		var item TSQueryItem
+		switch s[0] {
+		case '&', '|', '!', '(', ')':
+			item.Operator, s = s[:1], s[1:]
+		case '<':
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return nil, errTextSearchFormat
+			}
+			item.Operator, s = s[:end+1], s[end+1:]
+		default:
+			var err error
+			if item.Lexeme, s, err = scanLexeme(s, " &|!()<:"); err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(s, ":") {
+				end := 1
+				for end < len(s) && strings.IndexByte("*ABCDabcd", s[end]) >= 0 {
+					if s[end] == '*' {
+						item.Prefix = true
+					} else {
+						item.Weights += string(s[end] &^ 0x20)
+					}
+					end++
+				}
+				s = s[end:]
+			}
+		}
+		q = append(q, item)
+	}
+}
+
+// String returns q in the format Postgres uses.
+func (q TSQuery) String() string {
+	var b strings.Builder
+	for i, item := range q {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		if item.Operator != "" {
+			b.WriteString(item.Operator)
+			continue
+		}
+		writeLexeme(&b, item.Lexeme)
+		if item.Prefix || item.Weights != "" {
+			b.WriteByte(':')
+			if item.Prefix {
+				b.WriteByte('*')
+			}
+			b.WriteString(item.Weights)
+		}
+	}
+	return b.String()
+}
+
+// Implement Valuer interface.
+func (q TSQuery) Value() (driver.Value, error) {
+	if q == nil {
+		return nil, nil
+	}
+	return q.String(), nil
+}
+
+// Implement Scanner interface.
+func (q *TSQuery) Scan(src interface{}) error {
+	s, null, err := srcText(src, "TSQuery")
+	if err != nil || null {
+		*q = nil
+		return err
+	}
+	tq, err := ParseTSQuery(s)
+	if err != nil {
+		return err
+	}
+	*q = tq
+	return nil
+}
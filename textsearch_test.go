@@ -0,0 +1,82 @@
+package libpq_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func TestParseTSVector(t *testing.T) {
+	v, err := libpq.ParseTSVector(`'a' 'cat':3 'fat':2,4B 'it''s':1A`)
+	if err != nil {
+		t.Fatalf("Failed to parse tsvector: %s", err)
+	}
+	expect := libpq.TSVector{
+		{Word: "a"},
+		{Word: "cat", Positions: []libpq.LexemePosition{{Pos: 3, Weight: 'D'}}},
+		{Word: "fat", Positions: []libpq.LexemePosition{{Pos: 2, Weight: 'D'}, {Pos: 4, Weight: 'B'}}},
+		{Word: "it's", Positions: []libpq.LexemePosition{{Pos: 1, Weight: 'A'}}},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("Unexpected tsvector %+v", v)
+	}
+	if s := v.String(); s != `'a' 'cat':3 'fat':2,4B 'it''s':1A` {
+		t.Errorf("Unexpected String() %q", s)
+	}
+
+	for _, text := range []string{"'a", "'a':x", "'a':1,"} {
+		if _, err := libpq.ParseTSVector(text); err == nil {
+			t.Errorf("Expected error parsing %q", text)
+		}
+	}
+}
+
+func TestParseTSQuery(t *testing.T) {
+	q, err := libpq.ParseTSQuery(`'fat' & ( 'rat':AB | !'cat':* ) <-> 'x'`)
+	if err != nil {
+		t.Fatalf("Failed to parse tsquery: %s", err)
+	}
+	expect := libpq.TSQuery{
+		{Lexeme: "fat"},
+		{Operator: "&"},
+		{Operator: "("},
+		{Lexeme: "rat", Weights: "AB"},
+		{Operator: "|"},
+		{Operator: "!"},
+		{Lexeme: "cat", Prefix: true},
+		{Operator: ")"},
+		{Operator: "<->"},
+		{Lexeme: "x"},
+	}
+	if !reflect.DeepEqual(q, expect) {
+		t.Errorf("Unexpected tsquery %+v", q)
+	}
+}
+
+func TestTextSearch(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	var v libpq.TSVector
+	if err := db.QueryRow("select to_tsvector('english', 'The fat cats sat on the fat mat')").Scan(&v); err != nil {
+		t.Fatalf("Failed to Scan() tsvector: %s", err)
+	}
+	if len(v) != 4 || v[1].Word != "fat" || len(v[1].Positions) != 2 {
+		t.Errorf("Unexpected tsvector %+v", v)
+	}
+
+	var back libpq.TSVector
+	if err := db.QueryRow("select $1::tsvector", v).Scan(&back); err != nil || !reflect.DeepEqual(back, v) {
+		t.Errorf("tsvector %+v came back as %+v: %v", v, back, err)
+	}
+
+	var q libpq.TSQuery
+	if err := db.QueryRow("select to_tsquery('english', 'fat & (rat:AB | !cat:*)')").Scan(&q); err != nil {
+		t.Fatalf("Failed to Scan() tsquery: %s", err)
+	}
+	var matches bool
+	if err := db.QueryRow("select $1::tsvector @@ $2::tsquery", v, q).Scan(&matches); err != nil || !matches {
+		t.Errorf("tsquery %s did not match: %v", q, err)
+	}
+}
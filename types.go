@@ -0,0 +1,324 @@
+package libpq
+
+/*
+#include <stdlib.h>
+#include <libpq-fe.h>
+*/
+import "C"
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// Postgres type OID.
+type oid uint32
+
+// OIDs of built-in types never change, so there is no need to look them up.
+const (
+	oidBool        oid = 16
+	oidBytea       oid = 17
+	oidInt8        oid = 20
+	oidInt2        oid = 21
+	oidInt4        oid = 23
+	oidText        oid = 25
+	oidOid         oid = 26
+	oidJSON        oid = 114
+	oidXML         oid = 142
+	oidPoint       oid = 600
+	oidLseg        oid = 601
+	oidPath        oid = 602
+	oidBox         oid = 603
+	oidPolygon     oid = 604
+	oidLine        oid = 628
+	oidCIDR        oid = 650
+	oidFloat4      oid = 700
+	oidFloat8      oid = 701
+	oidCircle      oid = 718
+	oidMoney       oid = 790
+	oidMacaddr8    oid = 774
+	oidMacaddr     oid = 829
+	oidInet        oid = 869
+	oidBpchar      oid = 1042
+	oidVarchar     oid = 1043
+	oidDate        oid = 1082
+	oidTime        oid = 1083
+	oidTimestamp   oid = 1114
+	oidTimestampTz oid = 1184
+	oidInterval    oid = 1186
+	oidTimeTz      oid = 1266
+	oidBit         oid = 1560
+	oidVarbit      oid = 1562
+	oidNumeric     oid = 1700
+	oidUUID        oid = 2950
+	oidUUIDArray   oid = 2951
+	oidJSONB       oid = 3802
+)
+
+// pgType describes an extension or user-defined type from pg_type.
+type pgType struct {
+	oid    oid
+	name   string
+	schema string
+	kind   byte // typtype: 'b'ase, 'c'omposite, 'd'omain, 'e'num, 'r'ange, ...
+	elem   oid  // element type, for arrays
+	array  oid  // array type with this element type
+	base   oid  // base type, for domains
+	relid  oid  // pg_class entry, for composites
+
+	labels  []string // for enums, in sort order
+	element *pgType  // elem, if it is not a built-in type
+}
+
+// typeRegistry holds the non-built-in types of one database on one server.
+type typeRegistry struct {
+	identity string
+	byOid    map[oid]*pgType
+	byName   map[string]*pgType // by both name and schema.name
+
+	mu     sync.Mutex
+	misses map[typeMiss]time.Time // see reloadAfterMiss
+}
+
+// typeMiss is a lookup that failed: of a type by name or OID, or of one of
+// an enum's labels.
+type typeMiss struct {
+	name  string
+	oid   oid
+	label string
+}
+
+// How long a failed lookup is remembered, rather than reloading the registry
+// each time a type the server doesn't have (hstore, say) is looked up.
+const typeMissTTL = 5 * time.Second
+
+// lookup finds a type by (possibly schema-qualified) name.
+func (r *typeRegistry) lookup(name string) *pgType {
+	return r.byName[name]
+}
+
+func (r *typeRegistry) missedRecently(m typeMiss) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	at, ok := r.misses[m]
+	return ok && time.Since(at) < typeMissTTL
+}
+
+func (r *typeRegistry) noteMiss(m typeMiss) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.misses == nil {
+		r.misses = make(map[typeMiss]time.Time)
+	}
+	r.misses[m] = time.Now()
+}
+
+// reloadAfterMiss reloads c's registry after lookup m failed, in case the
+// type was created since we last looked, and reports whether found then
+// succeeds. A lookup that still fails is not retried for typeMissTTL.
+func (c *libpqConn) reloadAfterMiss(m typeMiss, found func() bool) bool {
+	if c.types.missedRecently(m) {
+		return false
+	}
+	if err := c.reloadTypes(); err != nil {
+		// not a miss, and noting it would hide the type from every
+		// connection to the server
+		return false
+	}
+	if found() {
+		return true
+	}
+	c.types.noteMiss(m)
+	return false
+}
+
+// typeOid returns the OID of the type with the given (possibly
+// schema-qualified) name, or 0 if there is no such type.
+func (c *libpqConn) typeOid(name string) oid {
+	t := c.types.lookup(name)
+	if t == nil {
+		c.reloadAfterMiss(typeMiss{name: name}, func() bool {
+			t = c.types.lookup(name)
+			return t != nil
+		})
+	}
+	if t == nil {
+		return 0
+	}
+	return t.oid
+}
+
+// validEnumLabel reports whether label is one of the labels of enum type t.
+func (c *libpqConn) validEnumLabel(t *pgType, label string) bool {
+	if t.hasLabel(label) {
+		return true
+	}
+	// maybe added (by ALTER TYPE ... ADD VALUE) since we last looked
+	return c.reloadAfterMiss(typeMiss{oid: t.oid, label: label}, func() bool {
+		t = c.types.byOid[t.oid]
+		return t != nil && t.hasLabel(label)
+	})
+}
+
+func (t *pgType) hasLabel(label string) bool {
+	for _, l := range t.labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// typeByOid describes the non-built-in type typ, or returns nil.
+func (c *libpqConn) typeByOid(typ oid) *pgType {
+	if typ < 16384 {
+		return nil
+	}
+	t := c.types.byOid[typ]
+	if t == nil {
+		c.reloadAfterMiss(typeMiss{oid: typ}, func() bool {
+			t = c.types.byOid[typ]
+			return t != nil
+		})
+	}
+	return t
+}
+
+// Identifies a database on a particular run of a particular server, so that
+// DSNs reaching the same database share a registry and a DSN reaching a
+// different server (after a failover or restart) does not. It only uses what
+// the server says about itself: how the client reached it (host name or
+// address, socket or TCP) doesn't matter.
+const identityQuery = `SELECT current_database(), pg_postmaster_start_time(),
+	current_setting('port')`
+
+// Types created by extensions and users get OIDs starting at 16384
+// (FirstNormalObjectId).
+const typesQuery = `SELECT t.oid, t.typname, n.nspname, t.typtype, t.typelem,
+	t.typarray, t.typbasetype, t.typrelid,
+	(SELECT array_agg(e.enumlabel ORDER BY e.enumsortorder)
+		FROM pg_enum e WHERE e.enumtypid = t.oid)
+	FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+	WHERE t.oid >= 16384`
+
+// loadTypes points c at the type registry for the server it is connected
+// to, querying pg_type if this is the first connection to that server.
+func (c *libpqConn) loadTypes() error {
+	cres, err := c.query(identityQuery)
+	if err != nil {
+		return err
+	}
+	identity := C.GoString(C.PQgetvalue(cres, 0, 0))
+	for i := 1; i < 3; i++ {
+		identity += "/" + C.GoString(C.PQgetvalue(cres, 0, C.int(i)))
+	}
+	C.PQclear(cres)
+
+	if c.types != nil {
+		if c.types.identity == identity {
+			return nil
+		}
+		// the server we were connected to has gone (or we failed over), so
+		// new connections won't need its registry
+		pqDriver.Lock()
+		delete(pqDriver.types, c.types.identity)
+		pqDriver.Unlock()
+	}
+
+	pqDriver.Lock()
+	reg, ok := pqDriver.types[identity]
+	pqDriver.Unlock()
+	if !ok {
+		if reg, err = c.queryTypes(identity); err != nil {
+			return err
+		}
+		pqDriver.Lock()
+		pqDriver.types[identity] = reg
+		pqDriver.Unlock()
+	}
+
+	c.types = reg
+	return nil
+}
+
+// reloadTypes refreshes the registry of the server c is connected to, e.g.
+// after an extension has been created.
+func (c *libpqConn) reloadTypes() error {
+	reg, err := c.queryTypes(c.types.identity)
+	if err != nil {
+		return err
+	}
+	pqDriver.Lock()
+	pqDriver.types[reg.identity] = reg
+	pqDriver.Unlock()
+	c.types = reg
+	return nil
+}
+
+func (c *libpqConn) queryTypes(identity string) (*typeRegistry, error) {
+	cres, err := c.query(typesQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer C.PQclear(cres)
+
+	reg := &typeRegistry{
+		identity: identity,
+		byOid:    make(map[oid]*pgType),
+		byName:   make(map[string]*pgType),
+	}
+	getOid := func(row, col int) oid {
+		n, perr := strconv.ParseUint(C.GoString(C.PQgetvalue(cres, C.int(row), C.int(col))), 10, 32)
+		if perr != nil && err == nil {
+			err = errors.New("libpq: invalid OID in pg_type")
+		}
+		return oid(n)
+	}
+	for row, nrows := 0, int(C.PQntuples(cres)); row < nrows; row++ {
+		t := &pgType{
+			oid:    getOid(row, 0),
+			name:   C.GoString(C.PQgetvalue(cres, C.int(row), 1)),
+			schema: C.GoString(C.PQgetvalue(cres, C.int(row), 2)),
+			kind:   byte(*C.PQgetvalue(cres, C.int(row), 3)),
+			elem:   getOid(row, 4),
+			array:  getOid(row, 5),
+			base:   getOid(row, 6),
+			relid:  getOid(row, 7),
+		}
+		if t.kind == 'e' && C.PQgetisnull(cres, C.int(row), 8) == 0 {
+			labels, perr := parseArray(C.GoString(C.PQgetvalue(cres, C.int(row), 8)), ',')
+			if perr != nil && err == nil {
+				err = perr
+			}
+			for _, l := range labels {
+				t.labels = append(t.labels, *l)
+			}
+		}
+		reg.byOid[t.oid] = t
+		reg.byName[t.schema+"."+t.name] = t
+		if _, ok := reg.byName[t.name]; !ok || t.schema == "public" {
+			reg.byName[t.name] = t
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range reg.byOid {
+		t.element = reg.byOid[t.elem]
+	}
+	return reg, nil
+}
+
+// query runs cmd, returning its result; the caller must PQclear it.
+func (c *libpqConn) query(cmd string) (*C.PGresult, error) {
+	ccmd := C.CString(cmd)
+	defer C.free(unsafe.Pointer(ccmd))
+	cres := C.PQexec(c.db, ccmd)
+	if err := resultError(cres); err != nil {
+		C.PQclear(cres)
+		return nil, err
+	}
+	return cres, nil
+}
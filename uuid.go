@@ -0,0 +1,98 @@
+package libpq
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+)
+
+// UUID is the value of a uuid column. Scan accepts both the text form and
+// the 16 raw bytes. UUIDs (and [16]byte values) passed as parameters are
+// sent as uuid.
+type UUID [16]byte
+
+// ParseUUID parses the text form of a UUID, with or without hyphens and
+// braces, as accepted by Postgres.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) >= 2 && s[0] == '{' && s[len(s)-1] == '}' {
+		s = s[1 : len(s)-1]
+	}
+
+	digits := make([]byte, 0, 32)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '-' {
+			continue
+		}
+		digits = append(digits, s[i])
+	}
+	if len(digits) != 32 {
+		return u, fmt.Errorf("libpq: invalid UUID %q", s)
+	}
+	if _, err := hex.Decode(u[:], digits); err != nil {
+		return u, fmt.Errorf("libpq: invalid UUID %q", s)
+	}
+	return u, nil
+}
+
+// String returns the canonical form, e.g.
+// "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11".
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// Implement Valuer interface.
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// Implement Scanner interface.
+func (u *UUID) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		v, err := ParseUUID(src)
+		*u = v
+		return err
+	case []byte:
+		if len(src) == 16 {
+			copy(u[:], src)
+			return nil
+		}
+		v, err := ParseUUID(string(src))
+		*u = v
+		return err
+	}
+	return fmt.Errorf("libpq: cannot scan %T into UUID", src)
+}
+
+// UUIDArray is the value of a uuid[] column. NULL elements are not allowed.
+type UUIDArray []UUID
+
+// Implement Valuer interface.
+func (a UUIDArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return formatUUIDArray(a), nil
+}
+
+// Implement Scanner interface.
+func (a *UUIDArray) Scan(src interface{}) error {
+	arr, err := scanArray(src, "UUIDArray", ParseUUID)
+	*a = arr
+	return err
+}
+
+func formatUUIDArray(a []UUID) string {
+	return valueArray(a, UUID.String)
+}
@@ -0,0 +1,79 @@
+package libpq_test
+
+import (
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+const testUUID = "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+
+func TestParseUUID(t *testing.T) {
+	for _, s := range []string{
+		testUUID,
+		"A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11",
+		"{a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11}",
+		"a0eebc999c0b4ef8bb6d6bb9bd380a11",
+	} {
+		u, err := libpq.ParseUUID(s)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", s, err)
+			continue
+		}
+		if u.String() != testUUID {
+			t.Errorf("Parsed %q as %s", s, u)
+		}
+	}
+
+	for _, s := range []string{"", "a0eebc99", testUUID + "0", "g0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"} {
+		if _, err := libpq.ParseUUID(s); err == nil {
+			t.Errorf("Expected error parsing %q", s)
+		}
+	}
+}
+
+func TestUUID(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+	mustExec(t, db, "create temp table test (u uuid)")
+
+	ref, _ := libpq.ParseUUID(testUUID)
+	for _, param := range []interface{}{ref, [16]byte(ref), testUUID} {
+		if _, err := db.Exec("insert into test(u) values($1)", param); err != nil {
+			t.Fatalf("Failed to insert %T as uuid: %s", param, err)
+		}
+	}
+
+	rows, err := db.Query("select u, u from test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	n := 0
+	for ; rows.Next(); n++ {
+		var u libpq.UUID
+		var s string
+		mustScan(t, rows, &u, &s)
+		if u != ref || s != testUUID {
+			t.Errorf("Unexpected uuid values %s, %s", u, s)
+		}
+	}
+	if n != 3 {
+		t.Fatalf("Expected 3 rows, got %d", n)
+	}
+}
+
+func TestUUIDArray(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	ref, _ := libpq.ParseUUID(testUUID)
+	var arr libpq.UUIDArray
+	err := db.QueryRow("select $1::uuid[] || $2::uuid", []libpq.UUID{ref}, ref).Scan(&arr)
+	if err != nil {
+		t.Fatalf("Failed to Scan() uuid[]: %s", err)
+	}
+	if len(arr) != 2 || arr[0] != ref || arr[1] != ref {
+		t.Fatalf("Unexpected uuid[] value %v", arr)
+	}
+}
@@ -0,0 +1,35 @@
+package libpq
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// XML is the value of an xml column. xml columns are returned as []byte, so
+// they can also be scanned into a []byte directly; XML exists so that
+// parameters are sent as text rather than as bytea.
+type XML []byte
+
+// Implement Valuer interface.
+func (x XML) Value() (driver.Value, error) {
+	if x == nil {
+		return nil, nil
+	}
+	return string(x), nil
+}
+
+// Implement Scanner interface.
+func (x *XML) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		*x = append(XML(nil), src...)
+		return nil
+	case string:
+		*x = XML(src)
+		return nil
+	case nil:
+		*x = nil
+		return nil
+	}
+	return fmt.Errorf("libpq: cannot scan %T into XML", src)
+}
@@ -0,0 +1,22 @@
+package libpq_test
+
+import (
+	"testing"
+
+	"github.com/jgallagher/go-libpq"
+)
+
+func TestXML(t *testing.T) {
+	db := getConn(t)
+	defer db.Close()
+
+	in := libpq.XML("<a>b &amp; c</a>")
+	var out libpq.XML
+	var raw []byte
+	if err := db.QueryRow("select $1::xml, $1::xml", in).Scan(&out, &raw); err != nil {
+		t.Fatalf("Failed to round-trip xml: %s", err)
+	}
+	if string(out) != string(in) || string(raw) != string(in) {
+		t.Errorf("xml %s came back as %s, %s", in, out, raw)
+	}
+}